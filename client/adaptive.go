@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// AdaptiveLimiter limits the number of concurrent calls, like a Bulkhead, but adjusts the limit to what the upstream
+// can handle. It uses an AIMD (additive increase, multiplicative decrease) algorithm: each call that is dropped
+// (i.e. it fails, returns a 429 or 5xx status code, or takes longer than LatencyThreshold) reduces the limit by
+// BackoffRatio. Each successful call made while at least half the limit is in use increases the limit by one.
+// A call whose deadline expires counts as dropped. Calls cancelled by the caller (e.g. the losing request of a Hedger)
+// don't change the limit:
+//
+//	c := &client.AdaptiveLimiter{Caller: &client.BaseClient{}, Options: client.AdaptiveLimiterOptions{
+//		InitialLimit:     10,
+//		LatencyThreshold: 500 * time.Millisecond,
+//		MaxQueued:        100,
+//	}}
+//
+// Calls beyond the limit wait in a bounded queue, as in a Bulkhead. Rejected calls fail with a *BulkheadError.
+type AdaptiveLimiter struct {
+	Caller
+	Options AdaptiveLimiterOptions
+	limiter *limiter
+	limit   float64
+	once    sync.Once
+	lock    sync.Mutex
+}
+
+var _ Caller = &AdaptiveLimiter{}
+
+// AdaptiveLimiterOptions contains options to alter AdaptiveLimiter behaviour
+type AdaptiveLimiterOptions struct {
+	// Name identifies the limiter in its metrics and in a BulkheadError. If empty, "adaptive" is used
+	Name string
+	// InitialLimit is the limit before any calls have been made. If zero, 10 is used
+	InitialLimit int
+	// MinLimit is the lowest limit. If zero, 1 is used
+	MinLimit int
+	// MaxLimit is the highest limit. If zero, 200 is used
+	MaxLimit int
+	// BackoffRatio is the factor by which the limit is reduced when a call is dropped. If zero, 0.9 is used
+	BackoffRatio float64
+	// LatencyThreshold is the latency above which a call is considered dropped. If zero, latency is ignored
+	LatencyThreshold time.Duration
+	// MaxQueued is the maximum number of calls waiting for a call to complete. If zero, calls are rejected
+	// as soon as the limit is reached
+	MaxQueued int
+	// QueueTimeout is the maximum time a call waits in the queue. If zero, calls wait until their context is done
+	QueueTimeout time.Duration
+	// Clock measures the latency of each call. If nil, the system clock is used
+	Clock Clock
+	// Application is the application label of the metrics
+	Application string
+	// Metrics records the limit, and the active and queued calls. If the metrics are nil, no metrics are recorded
+	Metrics BulkheadMetrics
+}
+
+// Clock tells the current time. Tests can provide a fake Clock to control the measured latency
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Do sends the request once the limit allows it, and adjusts the limit to the outcome of the call
+func (a *AdaptiveLimiter) Do(req *http.Request) (resp *http.Response, err error) {
+	a.once.Do(a.init)
+
+	if err = a.limiter.acquire(req.Context(), a.Options.QueueTimeout); err != nil {
+		if errors.Is(err, errQueueFull) || errors.Is(err, errQueueTimeout) {
+			a.Options.Metrics.reportRejection(a.Options.Application, a.Options.Name)
+			err = &BulkheadError{Group: a.Options.Name, QueueTimeout: errors.Is(err, errQueueTimeout)}
+		}
+		return nil, err
+	}
+	inFlight := a.limiter.inFlight()
+
+	start := a.Options.Clock.Now()
+	resp, err = withCancel(a.Caller.Do, req, sync.OnceFunc(a.limiter.release))
+	if errors.Is(err, context.Canceled) || errors.Is(req.Context().Err(), context.Canceled) {
+		// the caller gave up on the call (e.g. a cancelled hedged request): this says nothing about the upstream
+		return
+	}
+	a.update(inFlight, a.Options.Clock.Now().Sub(start), err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500)
+	return
+}
+
+// Limit returns the current limit
+func (a *AdaptiveLimiter) Limit() int {
+	a.once.Do(a.init)
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return int(a.limit)
+}
+
+func (a *AdaptiveLimiter) init() {
+	if a.Options.Name == "" {
+		a.Options.Name = "adaptive"
+	}
+	if a.Options.InitialLimit == 0 {
+		a.Options.InitialLimit = 10
+	}
+	if a.Options.MinLimit == 0 {
+		a.Options.MinLimit = 1
+	}
+	if a.Options.MaxLimit == 0 {
+		a.Options.MaxLimit = 200
+	}
+	if a.Options.BackoffRatio == 0 {
+		a.Options.BackoffRatio = 0.9
+	}
+	if a.Options.Clock == nil {
+		a.Options.Clock = systemClock{}
+	}
+	a.limit = float64(a.Options.InitialLimit)
+	a.limiter = &limiter{
+		limit:     a.Options.InitialLimit,
+		maxQueued: a.Options.MaxQueued,
+		onChange: func(limit, active, queued int) {
+			a.Options.Metrics.report(a.Options.Application, a.Options.Name, limit, active, queued)
+		},
+	}
+	a.limiter.changed()
+}
+
+// update adjusts the limit to the outcome of a call. inFlight is the number of calls in progress when the call started
+func (a *AdaptiveLimiter) update(inFlight int, latency time.Duration, failed bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	dropped := failed || (a.Options.LatencyThreshold > 0 && latency > a.Options.LatencyThreshold)
+	switch {
+	case dropped:
+		a.limit *= a.Options.BackoffRatio
+	case float64(2*inFlight) >= a.limit:
+		// only increase the limit if it is actually being used
+		a.limit++
+	default:
+		return
+	}
+	if a.limit < float64(a.Options.MinLimit) {
+		a.limit = float64(a.Options.MinLimit)
+	}
+	if a.limit > float64(a.Options.MaxLimit) {
+		a.limit = float64(a.Options.MaxLimit)
+	}
+	a.limiter.setLimit(int(a.limit))
+}
+
+// WithAdaptiveLimit limits the number of concurrent calls, adjusting the limit to the observed latency and errors,
+// like AdaptiveLimiter
+func WithAdaptiveLimit(options AdaptiveLimiterOptions) Middleware {
+	return Middleware{
+		Name: "adaptivelimit",
+		Wrap: func(next Caller) Caller {
+			return &AdaptiveLimiter{Caller: next, Options: options}
+		},
+	}
+}
@@ -0,0 +1,181 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	metrics := client.NewBulkheadMetrics("adaptive", "")
+	c := &client.AdaptiveLimiter{
+		Caller: fakeUpstream(clock),
+		Options: client.AdaptiveLimiterOptions{
+			InitialLimit:     2,
+			MinLimit:         1,
+			MaxLimit:         4,
+			LatencyThreshold: 100 * time.Millisecond,
+			Clock:            clock,
+			Application:      "foo",
+			Metrics:          metrics,
+		},
+	}
+	assert.Equal(t, 2, c.Limit())
+
+	// a successful call, using at least half the limit, increases the limit
+	_, _, err := get(c, http.MethodGet, "http://localhost/fast")
+	require.NoError(t, err)
+	assert.Equal(t, 3, c.Limit())
+
+	// the limit isn't increased if it isn't used
+	_, _, err = get(c, http.MethodGet, "http://localhost/fast")
+	require.NoError(t, err)
+	assert.Equal(t, 3, c.Limit())
+
+	// slow calls reduce the limit: 3 -> 2.7 -> 2.43 -> 2.187 -> 1.9683
+	for _, expected := range []int{2, 2, 2, 1} {
+		_, _, err = get(c, http.MethodGet, "http://localhost/slow")
+		require.NoError(t, err)
+		assert.Equal(t, expected, c.Limit())
+	}
+
+	// failed calls reduce the limit, but not below MinLimit
+	for i := 0; i < 5; i++ {
+		status, _, err := get(c, http.MethodGet, "http://localhost/fail")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+	}
+	assert.Equal(t, 1, c.Limit())
+
+	// the limit grows back, up to MaxLimit
+	for i := 0; i < 10; i++ {
+		_, _, err = getConcurrent(c, "http://localhost/fast", c.Limit())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 4, c.Limit())
+
+	m, err := tools.Collect(metrics.Limit)
+	require.NoError(t, err)
+	value, err := m.Gauge("adaptive_api_bulkhead_limit", prometheus.Labels{"application": "foo", "group": "adaptive"})
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, value)
+}
+
+func TestAdaptiveLimiter_Reject(t *testing.T) {
+	clock := &fakeClock{}
+	c := client.Build(fakeUpstream(clock), client.WithAdaptiveLimit(client.AdaptiveLimiterOptions{
+		Name:         "upstream",
+		InitialLimit: 1,
+		MaxLimit:     1,
+		Clock:        clock,
+	}))
+
+	// the call is in progress until its body is closed
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/fast", nil)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+
+	_, _, err = get(c, http.MethodGet, "http://localhost/fast")
+	assert.ErrorIs(t, err, client.ErrBulkheadRejected)
+	assert.Equal(t, "bulkhead upstream: queue full", err.Error())
+
+	require.NoError(t, resp.Body.Close())
+	_, _, err = get(c, http.MethodGet, "http://localhost/fast")
+	assert.NoError(t, err)
+}
+
+func TestAdaptiveLimiter_Cancelled(t *testing.T) {
+	clock := &fakeClock{}
+	c := &client.AdaptiveLimiter{
+		Caller: callerFunc(func(req *http.Request) (*http.Response, error) {
+			<-req.Context().Done()
+			return nil, req.Context().Err()
+		}),
+		Options: client.AdaptiveLimiterOptions{InitialLimit: 4, Clock: clock},
+	}
+
+	// calls cancelled by the caller don't reduce the limit
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/fast", nil)
+		_, err := c.Do(req)
+		assert.ErrorIs(t, err, context.Canceled)
+	}
+	assert.Equal(t, 4, c.Limit())
+
+	// calls whose deadline expires do: 4 -> 3.6 -> 3.24 -> 2.916 -> 2.6244 -> 2.36196
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/fast", nil)
+		_, err := c.Do(req)
+		cancel()
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+	assert.Equal(t, 2, c.Limit())
+}
+
+// fakeClock is a Clock that only advances when told to
+type fakeClock struct {
+	now  time.Time
+	lock sync.Mutex
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// fakeUpstream responds immediately. Calls to /slow advance the clock by 200 msec; calls to /fail return 503
+func fakeUpstream(clock *fakeClock) client.Caller {
+	return callerFunc(func(req *http.Request) (*http.Response, error) {
+		statusCode := http.StatusOK
+		switch req.URL.Path {
+		case "/slow":
+			clock.Advance(200 * time.Millisecond)
+		case "/fail":
+			statusCode = http.StatusServiceUnavailable
+		}
+		return &http.Response{StatusCode: statusCode, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	})
+}
+
+// getConcurrent holds count calls in progress, so the limiter sees its limit in use
+func getConcurrent(c client.Caller, url string, count int) (status int, body string, err error) {
+	var bodies []io.Closer
+	for i := 0; i < count-1; i++ {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		resp, err2 := c.Do(req)
+		if err2 != nil {
+			return 0, "", errors.Join(err2, closeAll(bodies))
+		}
+		bodies = append(bodies, resp.Body)
+	}
+	status, body, err = get(c, http.MethodGet, url)
+	return status, body, errors.Join(err, closeAll(bodies))
+}
+
+func closeAll(closers []io.Closer) (err error) {
+	for _, closer := range closers {
+		err = errors.Join(err, closer.Close())
+	}
+	return
+}
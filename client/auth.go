@@ -0,0 +1,338 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HeaderCaller adds a set of static headers (e.g. an API key) to each request:
+//
+//	c := &client.HeaderCaller{Caller: &client.BaseClient{}, Header: http.Header{"X-Api-Key": []string{key}}}
+//
+// The request is cloned before the headers are added, so the caller's request is not modified. HeaderCaller also
+// records a hash of the headers as the request's identity (see WithIdentity), so a Cacher never shares cached
+// responses between HeaderCallers with different headers.
+type HeaderCaller struct {
+	Caller
+	// Header contains the headers to add to each request. Existing headers with the same name are replaced
+	Header http.Header
+}
+
+var _ Caller = &HeaderCaller{}
+
+// Do adds the headers to the request and sends it
+func (h *HeaderCaller) Do(req *http.Request) (*http.Response, error) {
+	req = req.Clone(WithIdentity(req.Context(), headerIdentity(h.Header)))
+	for key, values := range h.Header {
+		req.Header[http.CanonicalHeaderKey(key)] = values
+	}
+	return h.Caller.Do(req)
+}
+
+func headerIdentity(header http.Header) string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, http.CanonicalHeaderKey(key))
+	}
+	sort.Strings(keys)
+	var identity strings.Builder
+	for _, key := range keys {
+		identity.WriteString(key + ":" + strings.Join(header.Values(key), ",") + "\n")
+	}
+	return identity.String()
+}
+
+// Token is an access token, as returned by a TokenSource
+type Token struct {
+	// AccessToken is the token sent to the API
+	AccessToken string
+	// TokenType is the type of the token. If empty, "Bearer" is used
+	TokenType string
+	// Expiry is the time at which the token expires. A zero Expiry means the token doesn't expire
+	Expiry time.Time
+}
+
+// expiresWithin reports whether the token expires within the specified duration
+func (t *Token) expiresWithin(d time.Duration) bool {
+	return !t.Expiry.IsZero() && time.Until(t.Expiry) < d
+}
+
+func (t *Token) header() string {
+	tokenType := t.TokenType
+	if tokenType == "" {
+		tokenType = "Bearer"
+	}
+	return tokenType + " " + t.AccessToken
+}
+
+// TokenSource returns a (new) access token
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+// TokenSourceFunc is an adapter to allow the use of an ordinary function as a TokenSource
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+// Token calls f(ctx)
+func (f TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return f(ctx)
+}
+
+// BearerTokenCaller adds an access token, obtained from a TokenSource, to the Authorization header of each request:
+//
+//	c := &client.BearerTokenCaller{
+//		Caller: &client.BaseClient{},
+//		Source: &client.ClientCredentials{TokenURL: tokenURL, ClientID: id, ClientSecret: secret},
+//	}
+//
+// The token is reused until it is about to expire. Once the token expires within RefreshBefore, BearerTokenCaller
+// refreshes it in the background, while still using the current token. Once the token has expired, requests wait
+// for the new token. Concurrent requests share a single refresh, which fails if it takes longer than RefreshTimeout.
+// If the API responds with 401 Unauthorized, the token is discarded, so the next request gets a new token.
+type BearerTokenCaller struct {
+	Caller
+	// Source provides the access tokens
+	Source TokenSource
+	// RefreshBefore is how long before the token's expiry it is refreshed. If zero, DefaultRefreshBefore is used
+	RefreshBefore time.Duration
+	// RefreshTimeout is the maximum time a token refresh may take. If zero, DefaultRefreshTimeout is used
+	RefreshTimeout time.Duration
+	// Application is the application label of the token metrics
+	Application string
+	// Metrics records token refreshes. If the metrics are nil, no metrics are recorded
+	Metrics TokenMetrics
+	token   *Token
+	refresh *tokenRefresh
+	lock    sync.Mutex
+}
+
+var _ Caller = &BearerTokenCaller{}
+
+// DefaultRefreshBefore is the default duration before a token's expiry at which BearerTokenCaller refreshes it
+const DefaultRefreshBefore = 30 * time.Second
+
+// DefaultRefreshTimeout is the default maximum time a BearerTokenCaller waits for a token refresh
+const DefaultRefreshTimeout = 10 * time.Second
+
+// tokenRefresh holds the outcome of a token refresh. done is closed when the refresh has completed
+type tokenRefresh struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+// Do adds the access token to the request and sends it
+func (b *BearerTokenCaller) Do(req *http.Request) (resp *http.Response, err error) {
+	var token *Token
+	if token, err = b.getToken(req.Context()); err != nil {
+		return nil, fmt.Errorf("token: %w", err)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", token.header())
+
+	if resp, err = b.Caller.Do(req); err == nil && resp.StatusCode == http.StatusUnauthorized {
+		b.invalidate(token)
+	}
+	return
+}
+
+func (b *BearerTokenCaller) getToken(ctx context.Context) (*Token, error) {
+	refreshBefore := b.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = DefaultRefreshBefore
+	}
+
+	b.lock.Lock()
+	token := b.token
+	if token != nil && !token.expiresWithin(refreshBefore) {
+		b.lock.Unlock()
+		return token, nil
+	}
+	refresh := b.refresh
+	if refresh == nil {
+		refresh = &tokenRefresh{done: make(chan struct{})}
+		b.refresh = refresh
+		// the refresh is shared with other requests: don't cancel it if this request is cancelled
+		go b.doRefresh(context.WithoutCancel(ctx), refresh)
+	}
+	b.lock.Unlock()
+
+	if token != nil && !token.expiresWithin(0) {
+		return token, nil
+	}
+
+	select {
+	case <-refresh.done:
+		return refresh.token, refresh.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (b *BearerTokenCaller) doRefresh(ctx context.Context, refresh *tokenRefresh) {
+	timeout := b.RefreshTimeout
+	if timeout == 0 {
+		timeout = DefaultRefreshTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	refresh.token, refresh.err = b.Source.Token(ctx)
+	if refresh.err == nil && refresh.token == nil {
+		refresh.err = errors.New("token source returned no token")
+	}
+	b.Metrics.reportRefresh(b.Application, time.Since(start), refresh.err)
+
+	b.lock.Lock()
+	if refresh.err == nil {
+		b.token = refresh.token
+	}
+	b.refresh = nil
+	b.lock.Unlock()
+	close(refresh.done)
+}
+
+func (b *BearerTokenCaller) invalidate(token *Token) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.token == token {
+		b.token = nil
+	}
+}
+
+// ClientCredentials is a TokenSource that obtains access tokens using the OAuth2 client credentials flow
+type ClientCredentials struct {
+	// Caller sends the token requests. If nil, a BaseClient is used
+	Caller Caller
+	// TokenURL is the URL of the token endpoint
+	TokenURL string
+	// ClientID is the client's ID
+	ClientID string
+	// ClientSecret is the client's secret
+	ClientSecret string
+	// Scopes contains the requested scopes. If empty, no scope is requested
+	Scopes []string
+	// EndpointParams contains additional parameters to send to the token endpoint
+	EndpointParams url.Values
+	// CredentialsInBody sends the client credentials as form parameters, rather than with HTTP Basic authentication
+	CredentialsInBody bool
+}
+
+var _ TokenSource = &ClientCredentials{}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// Token requests a new access token from the token endpoint
+func (c *ClientCredentials) Token(ctx context.Context) (token *Token, err error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(c.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	for key, values := range c.EndpointParams {
+		form[key] = append(form[key], values...)
+	}
+	if c.CredentialsInBody {
+		form.Set("client_id", c.ClientID)
+		form.Set("client_secret", c.ClientSecret)
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, http.MethodPost, c.TokenURL, strings.NewReader(form.Encode())); err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !c.CredentialsInBody {
+		req.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+
+	caller := c.Caller
+	if caller == nil {
+		caller = &BaseClient{}
+	}
+	var resp *http.Response
+	if resp, err = caller.Do(req); err != nil {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if !isSuccess(resp) {
+		return nil, newHTTPError(resp)
+	}
+
+	var response tokenResponse
+	if err = json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	if response.AccessToken == "" {
+		return nil, fmt.Errorf("token endpoint returned no access token")
+	}
+	token = &Token{AccessToken: response.AccessToken, TokenType: response.TokenType}
+	if response.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	return
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context that records the identity (e.g. the credentials) under which a request is made.
+// Cacher includes the identity, and the request's Authorization and Cookie headers, in its cache key, so requests
+// made under different identities never share cached responses. HeaderCaller sets the identity automatically.
+func WithIdentity(ctx context.Context, identity string) context.Context {
+	if current, ok := ctx.Value(identityKey{}).(string); ok {
+		identity = current + "\n" + identity
+	}
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// requestIdentity returns a hash of the identity under which the request is made, or an empty string if the
+// request holds no identity or credentials
+func requestIdentity(req *http.Request) string {
+	identity, _ := req.Context().Value(identityKey{}).(string)
+	for _, header := range []string{"Authorization", "Cookie"} {
+		if value := req.Header.Get(header); value != "" {
+			identity += "\n" + header + ":" + value
+		}
+	}
+	if identity == "" {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(identity))
+	return hex.EncodeToString(hash[:16])
+}
+
+// WithHeaders adds static headers to each request, like HeaderCaller
+func WithHeaders(header http.Header) Middleware {
+	return Middleware{
+		Name: "headers",
+		Wrap: func(next Caller) Caller {
+			return &HeaderCaller{Caller: next, Header: header}
+		},
+	}
+}
+
+// WithBearerToken adds an access token from source to each request, like BearerTokenCaller
+func WithBearerToken(source TokenSource, application string, metrics TokenMetrics) Middleware {
+	return Middleware{
+		Name: "auth",
+		Wrap: func(next Caller) Caller {
+			return &BearerTokenCaller{Caller: next, Source: source, Application: application, Metrics: metrics}
+		},
+	}
+}
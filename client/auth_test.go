@@ -0,0 +1,234 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/clambin/cache"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHeaderCaller(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("X-Api-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})})
+	defer s.Close()
+
+	c := &client.HeaderCaller{Caller: &client.BaseClient{}, Header: http.Header{"x-api-key": []string{"secret"}}}
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo", nil)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Empty(t, req.Header.Get("X-Api-Key"))
+}
+
+func TestCacher_Identity(t *testing.T) {
+	s := &server{}
+	srv := httptest.NewServer(http.HandlerFunc(s.handle))
+	defer srv.Close()
+
+	cacher := &client.Cacher{
+		Caller: &client.BaseClient{},
+		Table:  client.CacheTable{Table: []client.CacheTableEntry{{Endpoint: "/foo"}}},
+		Cache:  cache.New[string, []byte](time.Minute, 0),
+	}
+	user1 := &client.HeaderCaller{Caller: cacher, Header: http.Header{"X-Api-Key": []string{"user1"}}}
+	user2 := &client.HeaderCaller{Caller: cacher, Header: http.Header{"X-Api-Key": []string{"user2"}}}
+
+	value, err := doCall2(user1, srv.URL+"/foo")
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+	value, err = doCall2(user2, srv.URL+"/foo")
+	require.NoError(t, err)
+	assert.Equal(t, 2, value)
+	value, err = doCall2(user1, srv.URL+"/foo")
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/foo", nil)
+	req.Header.Set("Authorization", "Bearer 123")
+	resp, err := cacher.Do(req)
+	require.NoError(t, err)
+	var response serverResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	_ = resp.Body.Close()
+	assert.Equal(t, 3, response.Counter)
+
+	value, err = doCall2(cacher, srv.URL+"/foo")
+	require.NoError(t, err)
+	assert.Equal(t, 4, value)
+}
+
+func TestBearerTokenCaller(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer 1" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})})
+	defer s.Close()
+
+	var refreshes atomic.Int32
+	source := client.TokenSourceFunc(func(_ context.Context) (*client.Token, error) {
+		time.Sleep(10 * time.Millisecond)
+		count := refreshes.Add(1)
+		return &client.Token{AccessToken: strconv.Itoa(int(count)), Expiry: time.Now().Add(time.Hour)}, nil
+	})
+	metrics := client.NewTokenMetrics("bearer", "")
+	c := &client.BearerTokenCaller{Caller: &client.BaseClient{}, Source: source, Application: "foo", Metrics: metrics}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo", nil)
+			resp, err := c.Do(req)
+			require.NoError(t, err)
+			_ = resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), refreshes.Load())
+
+	m, err := tools.Collect(metrics.Refreshes)
+	require.NoError(t, err)
+	value, err := m.Counter("bearer_token_refreshes_total", prometheus.Labels{"application": "foo", "result": "success"})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+
+	// the next token is rejected: BearerTokenCaller discards it and gets a new one on the next call
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo", nil)
+	c.RefreshBefore = 2 * time.Hour
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Eventually(t, func() bool { return refreshes.Load() == 2 }, time.Second, 10*time.Millisecond)
+
+	c.RefreshBefore = 0
+	resp, err = c.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = c.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, int32(3), refreshes.Load())
+}
+
+func TestBearerTokenCaller_Failure(t *testing.T) {
+	metrics := client.NewTokenMetrics("bearer_failure", "")
+	c := &client.BearerTokenCaller{
+		Caller:      &client.BaseClient{},
+		Source:      client.TokenSourceFunc(func(_ context.Context) (*client.Token, error) { return nil, errors.New("fail") }),
+		Application: "foo",
+		Metrics:     metrics,
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/foo", nil)
+	_, err := c.Do(req)
+	assert.EqualError(t, err, "token: fail")
+
+	m, err := tools.Collect(metrics.Refreshes)
+	require.NoError(t, err)
+	value, err := m.Counter("bearer_failure_token_refreshes_total", prometheus.Labels{"application": "foo", "result": "failure"})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+}
+
+func TestBearerTokenCaller_NoToken(t *testing.T) {
+	c := &client.BearerTokenCaller{
+		Caller: &client.BaseClient{},
+		Source: client.TokenSourceFunc(func(_ context.Context) (*client.Token, error) { return nil, nil }),
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/foo", nil)
+	_, err := c.Do(req)
+	assert.EqualError(t, err, "token: token source returned no token")
+}
+
+func TestBearerTokenCaller_RefreshTimeout(t *testing.T) {
+	var calls atomic.Int32
+	hang := make(chan struct{})
+	s := stubserver.New(stubserver.Route{Method: http.MethodPost, Path: "/token", Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if calls.Add(1) == 1 {
+			// the first refresh hangs
+			<-hang
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+	})})
+	defer s.Close()
+	defer close(hang)
+
+	c := &client.BearerTokenCaller{
+		Caller:         &client.BaseClient{},
+		Source:         &client.ClientCredentials{TokenURL: s.URL + "/token", ClientID: "id", ClientSecret: "secret"},
+		RefreshTimeout: 100 * time.Millisecond,
+	}
+	req, _ := http.NewRequest(http.MethodPost, s.URL+"/token", nil)
+	_, err := c.Do(req)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the hanging refresh doesn't block later refreshes
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func TestClientCredentials(t *testing.T) {
+	s := stubserver.New(
+		stubserver.Route{Method: http.MethodPost, Path: "/token", Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			id, secret, ok := req.BasicAuth()
+			if !ok || id != "id" || secret != "secret" || req.FormValue("grant_type") != "client_credentials" {
+				http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"access_token": "token-" + req.FormValue("scope"),
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			})
+		})},
+		stubserver.Route{Path: "/api", Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("Authorization") != "Bearer token-read write" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		})},
+	)
+	defer s.Close()
+
+	source := &client.ClientCredentials{TokenURL: s.URL + "/token", ClientID: "id", ClientSecret: "secret", Scopes: []string{"read", "write"}}
+	token, err := source.Token(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "token-read write", token.AccessToken)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), token.Expiry, time.Minute)
+
+	c := client.Build(nil, client.WithBearerToken(source, "foo", client.TokenMetrics{}))
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/api", nil)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	source.ClientSecret = "invalid"
+	_, err = source.Token(context.Background())
+	require.Error(t, err)
+	assert.ErrorIs(t, err, client.ErrClientError)
+}
@@ -0,0 +1,258 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Bulkhead limits the number of concurrent calls to groups of endpoints, so a slow or fragile upstream can't tie up
+// all of an application's resources:
+//
+//	c := &client.Bulkhead{Caller: &client.BaseClient{}, Options: client.BulkheadOptions{
+//		Groups: []client.BulkheadGroup{
+//			{Name: "reports", Endpoint: "/reports/.*", IsRegExp: true, MaxConcurrent: 2, MaxQueued: 10, QueueTimeout: time.Second},
+//		},
+//	}}
+//
+// Once a group's MaxConcurrent calls are in progress, further calls wait in the group's queue. If the queue is full,
+// or a call waits longer than QueueTimeout, the call is rejected with a *BulkheadError. If the request's context is
+// cancelled while waiting, Do returns the context's error. A call is in progress until its response's body is closed.
+//
+// Groups are matched like CacheTableEntry. The first matching group applies. Requests that don't match any group
+// are not limited.
+type Bulkhead struct {
+	Caller
+	Options  BulkheadOptions
+	groups   []BulkheadGroup
+	limiters []*limiter
+	once     sync.Once
+}
+
+var _ Caller = &Bulkhead{}
+
+// BulkheadOptions contains options to alter Bulkhead behaviour
+type BulkheadOptions struct {
+	// Groups contains the endpoint groups to limit
+	Groups []BulkheadGroup
+	// Application is the application label of the metrics
+	Application string
+	// Metrics records the active and queued calls of each group. If the metrics are nil, no metrics are recorded
+	Metrics BulkheadMetrics
+}
+
+// BulkheadGroup limits the concurrent calls to a group of endpoints. If the Endpoint is a regular expression,
+// IsRegExp must be set. Bulkhead will panic if the regular expression is invalid.
+type BulkheadGroup struct {
+	// Name of the group, as reported in the metrics and in a BulkheadError
+	Name string
+	// Endpoint is the URL Path of the group's requests. Can be a literal path, or a regular expression.
+	// In the latter case, set IsRegExp to true
+	Endpoint string
+	// Methods is the list of HTTP Methods of the group's requests. If empty, requests for any method match.
+	Methods []string
+	// IsRegExp indicated the Endpoint is a regular expression.
+	IsRegExp bool
+	// MaxConcurrent is the maximum number of concurrent calls. If zero, 1 is used
+	MaxConcurrent int
+	// MaxQueued is the maximum number of calls waiting for a call to complete. If zero, calls are rejected
+	// as soon as MaxConcurrent calls are in progress
+	MaxQueued int
+	// QueueTimeout is the maximum time a call waits in the queue. If zero, calls wait until their context is done
+	QueueTimeout   time.Duration
+	compiledRegExp *regexp.Regexp
+}
+
+// ErrBulkheadRejected matches any BulkheadError
+var ErrBulkheadRejected = errors.New("rejected by bulkhead")
+
+// BulkheadError is returned when a Bulkhead rejects a call
+type BulkheadError struct {
+	// Group that rejected the call
+	Group string
+	// QueueTimeout is set if the call waited in the queue for longer than the group's QueueTimeout.
+	// Otherwise, the queue was full
+	QueueTimeout bool
+}
+
+// Error implements the error interface
+func (e *BulkheadError) Error() string {
+	reason := "queue full"
+	if e.QueueTimeout {
+		reason = "queue timeout"
+	}
+	return "bulkhead " + e.Group + ": " + reason
+}
+
+// Is reports whether the target is ErrBulkheadRejected
+func (e *BulkheadError) Is(target error) bool {
+	return target == ErrBulkheadRejected
+}
+
+// Do sends the request once the request's group allows it
+func (b *Bulkhead) Do(req *http.Request) (*http.Response, error) {
+	b.once.Do(b.init)
+
+	index := -1
+	for i, group := range b.groups {
+		if matchesEndpoint(group.Endpoint, group.compiledRegExp, req) && matchesMethods(group.Methods, req) {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return b.Caller.Do(req)
+	}
+
+	group := b.groups[index]
+	if err := b.limiters[index].acquire(req.Context(), group.QueueTimeout); err != nil {
+		if errors.Is(err, errQueueFull) || errors.Is(err, errQueueTimeout) {
+			b.Options.Metrics.reportRejection(b.Options.Application, group.Name)
+			err = &BulkheadError{Group: group.Name, QueueTimeout: errors.Is(err, errQueueTimeout)}
+		}
+		return nil, err
+	}
+	return withCancel(b.Caller.Do, req, sync.OnceFunc(b.limiters[index].release))
+}
+
+func (b *Bulkhead) init() {
+	b.groups = append([]BulkheadGroup{}, b.Options.Groups...)
+	for index := range b.groups {
+		group := &b.groups[index]
+		if group.IsRegExp {
+			group.compiledRegExp = compileEndpoint("bulkhead", group.Endpoint)
+		}
+		maxConcurrent := group.MaxConcurrent
+		if maxConcurrent == 0 {
+			maxConcurrent = 1
+		}
+		name := group.Name
+		b.limiters = append(b.limiters, &limiter{
+			limit:     maxConcurrent,
+			maxQueued: group.MaxQueued,
+			onChange: func(limit, active, queued int) {
+				b.Options.Metrics.report(b.Options.Application, name, limit, active, queued)
+			},
+		})
+		b.limiters[index].changed()
+	}
+}
+
+var (
+	errQueueFull    = errors.New("queue full")
+	errQueueTimeout = errors.New("queue timeout")
+)
+
+// limiter limits the number of concurrent calls to limit. Once reached, up to maxQueued calls wait, in order,
+// for a call to complete.
+type limiter struct {
+	limit     int
+	maxQueued int
+	active    int
+	waiters   []chan struct{}
+	onChange  func(limit, active, queued int)
+	lock      sync.Mutex
+}
+
+// acquire waits until the call can proceed. The caller must call release once the call has completed.
+func (l *limiter) acquire(ctx context.Context, timeout time.Duration) (err error) {
+	l.lock.Lock()
+	if l.active < l.limit && len(l.waiters) == 0 {
+		l.active++
+		l.changed()
+		l.lock.Unlock()
+		return nil
+	}
+	if len(l.waiters) >= l.maxQueued {
+		l.lock.Unlock()
+		return errQueueFull
+	}
+	waiter := make(chan struct{})
+	l.waiters = append(l.waiters, waiter)
+	l.changed()
+	l.lock.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case <-waiter:
+		return nil
+	case <-ctx.Done():
+		err = ctx.Err()
+	case <-expired:
+		err = errQueueTimeout
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for index := range l.waiters {
+		if l.waiters[index] == waiter {
+			l.waiters = append(l.waiters[:index], l.waiters[index+1:]...)
+			l.changed()
+			return err
+		}
+	}
+	// the call was allowed to proceed while it gave up: hand the slot to the next waiter
+	l.active--
+	l.dispatch()
+	return err
+}
+
+// release marks a call as completed
+func (l *limiter) release() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.active--
+	l.dispatch()
+}
+
+// setLimit changes the maximum number of concurrent calls
+func (l *limiter) setLimit(limit int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if limit != l.limit {
+		l.limit = limit
+		l.dispatch()
+	}
+}
+
+// inFlight returns the number of calls in progress
+func (l *limiter) inFlight() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.active
+}
+
+// dispatch allows waiting calls to proceed, as long as the limit allows it. Must be called with the lock held.
+func (l *limiter) dispatch() {
+	for l.active < l.limit && len(l.waiters) > 0 {
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+		l.active++
+	}
+	l.changed()
+}
+
+func (l *limiter) changed() {
+	if l.onChange != nil {
+		l.onChange(l.limit, l.active, len(l.waiters))
+	}
+}
+
+// WithBulkhead limits the number of concurrent calls to groups of endpoints, like Bulkhead
+func WithBulkhead(options BulkheadOptions) Middleware {
+	return Middleware{
+		Name: "bulkhead",
+		Wrap: func(next Caller) Caller {
+			return &Bulkhead{Caller: next, Options: options}
+		},
+	}
+}
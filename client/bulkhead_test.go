@@ -0,0 +1,117 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBulkhead(t *testing.T) {
+	blocked := make(chan struct{})
+	s := stubserver.New(
+		stubserver.Route{Path: "/reports/1", Handler: http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+			<-blocked
+		})},
+		stubserver.Route{Path: "/foo"},
+	)
+	defer s.Close()
+	metrics := client.NewBulkheadMetrics("bulkhead", "")
+	c := &client.Bulkhead{Caller: &client.BaseClient{}, Options: client.BulkheadOptions{
+		Groups: []client.BulkheadGroup{
+			{Name: "reports", Endpoint: "/reports/.+", IsRegExp: true, MaxConcurrent: 1, MaxQueued: 1},
+		},
+		Application: "foo",
+		Metrics:     metrics,
+	}}
+	labels := prometheus.Labels{"application": "foo", "group": "reports"}
+	gauge := func(name string, collector prometheus.Collector) float64 {
+		m, err := tools.Collect(collector)
+		require.NoError(t, err)
+		value, err := m.Gauge(name, labels)
+		require.NoError(t, err)
+		return value
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, _, err := get(c, http.MethodGet, s.URL+"/reports/1")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, status)
+		}()
+	}
+	require.Eventually(t, func() bool { return gauge("bulkhead_api_bulkhead_queued", metrics.Queued) == 1 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1.0, gauge("bulkhead_api_bulkhead_active", metrics.Active))
+
+	// the queue is full
+	_, _, err := get(c, http.MethodGet, s.URL+"/reports/1")
+	require.Error(t, err)
+	assert.ErrorIs(t, err, client.ErrBulkheadRejected)
+	var bulkheadErr *client.BulkheadError
+	require.True(t, errors.As(err, &bulkheadErr))
+	assert.Equal(t, "reports", bulkheadErr.Group)
+	assert.False(t, bulkheadErr.QueueTimeout)
+	assert.Equal(t, "bulkhead reports: queue full", err.Error())
+
+	// requests that don't match a group aren't limited
+	_, _, err = get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+
+	close(blocked)
+	wg.Wait()
+	assert.Equal(t, 0.0, gauge("bulkhead_api_bulkhead_active", metrics.Active))
+	assert.Equal(t, 0.0, gauge("bulkhead_api_bulkhead_queued", metrics.Queued))
+	s.AssertCalls(t, http.MethodGet, "/reports/1", 2)
+
+	m, err := tools.Collect(metrics.Rejections)
+	require.NoError(t, err)
+	value, err := m.Counter("bulkhead_api_bulkhead_rejections_total", labels)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+}
+
+func TestBulkhead_Queue(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo"})
+	defer s.Close()
+	c := client.Build(nil, client.WithBulkhead(client.BulkheadOptions{
+		Groups: []client.BulkheadGroup{{Name: "foo", Endpoint: "/foo", MaxQueued: 5, QueueTimeout: 20 * time.Millisecond}},
+	}))
+
+	// the call remains in progress until its body is closed
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo", nil)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+
+	_, _, err = get(c, http.MethodGet, s.URL+"/foo")
+	var bulkheadErr *client.BulkheadError
+	require.True(t, errors.As(err, &bulkheadErr))
+	assert.True(t, bulkheadErr.QueueTimeout)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ = http.NewRequestWithContext(ctx, http.MethodGet, s.URL+"/foo", nil)
+	_, err = c.Do(req)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.False(t, errors.Is(err, client.ErrBulkheadRejected))
+
+	// a queued call proceeds once the call in progress completes
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = resp.Body.Close()
+	}()
+	_, _, err = get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+	_, _, err = get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+}
@@ -18,26 +18,32 @@ type Cacher struct {
 
 var _ Caller = &Cacher{}
 
-// NewCacher creates a new Cacher.  It will also use InstrumentedClient to measure API call performance statistics.
+// NewCacher creates a new Cacher. It also measures API call performance statistics, like InstrumentedClient.
+// NewCacher is a shorthand for:
+//
+//	client.Build(&client.BaseClient{HTTPClient: httpClient},
+//		client.WithCache(cacheEntries, cacheExpiry, cacheCleanup),
+//		client.WithInstrumentation(application, options),
+//	)
+//
+// Use Build to assemble any other combination of Callers.
 func NewCacher(httpClient *http.Client, application string, options Options, cacheEntries []CacheTableEntry, cacheExpiry, cacheCleanup time.Duration) *Cacher {
-	return &Cacher{
-		Caller: &InstrumentedClient{
-			BaseClient:  BaseClient{HTTPClient: httpClient},
-			Application: application,
-			Options:     options,
-		},
-		Table: CacheTable{Table: cacheEntries},
-		Cache: cache.New[string, []byte](cacheExpiry, cacheCleanup),
-	}
+	return Build(&BaseClient{HTTPClient: httpClient},
+		WithCache(cacheEntries, cacheExpiry, cacheCleanup),
+		WithInstrumentation(application, options),
+	).Caller.(*Cacher)
 }
 
 // Do sends the request and caches the response for future use.
 // If a (non-expired) cached response exists for the request's URL, it is returned instead.
 //
-// Note: only the request's URL is used to find a cached version. Currently, it does not consider the request's method (i.e. GET/PUT/etc).
+// Note: only the request's URL and identity are used to find a cached version. Currently, it does not consider the request's method (i.e. GET/PUT/etc).
+// The identity consists of the request's Authorization and Cookie headers, and any identity recorded with WithIdentity,
+// so requests made with different credentials never share a cached response.
 func (c *Cacher) Do(req *http.Request) (resp *http.Response, err error) {
 	key := cacheKey(req)
 	body, found := c.Cache.Get(key)
+	reportCacheLookup(req, found)
 	if found {
 		return cachedResponse(body, req)
 	}
@@ -71,7 +77,11 @@ func (c *Cacher) shouldCache(r *http.Request) (cache bool, expiry time.Duration)
 }
 
 func cacheKey(r *http.Request) string {
-	return r.URL.String()
+	key := r.URL.String()
+	if identity := requestIdentity(r); identity != "" {
+		key += " " + identity
+	}
+	return key
 }
 
 func cachedResponse(b []byte, r *http.Request) (resp *http.Response, err error) {
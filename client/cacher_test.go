@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/stubserver"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"net/http"
@@ -101,6 +102,33 @@ func TestCacher_Do_MultipleEndpoints(t *testing.T) {
 
 }
 
+func TestCacher_Do_CacheHits(t *testing.T) {
+	s := stubserver.New(
+		stubserver.Route{Path: "/foo", JSON: serverResponse{Counter: 1}},
+		stubserver.Route{Path: "/bar", JSON: serverResponse{Counter: 2}},
+	)
+	defer s.Close()
+	c := client.NewCacher(
+		nil, "foo", client.Options{},
+		[]client.CacheTableEntry{
+			{Endpoint: "/foo", Methods: []string{http.MethodGet}},
+		},
+		time.Minute, 0,
+	)
+
+	for i := 0; i < 3; i++ {
+		value, err := doCall2(c, s.URL+"/foo")
+		require.NoError(t, err)
+		assert.Equal(t, 1, value)
+		value, err = doCall2(c, s.URL+"/bar")
+		require.NoError(t, err)
+		assert.Equal(t, 2, value)
+	}
+
+	s.AssertCalls(t, http.MethodGet, "/foo", 1)
+	s.AssertCalls(t, http.MethodGet, "/bar", 3)
+}
+
 type server struct {
 	counter int
 }
@@ -0,0 +1,128 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// APIClient holds the configuration to call an API with Call
+type APIClient struct {
+	// Caller sends the requests. This can be any Caller chain (e.g. a Cacher). If nil, a BaseClient is used
+	Caller Caller
+	// BaseURL is the URL of the API. Call joins it with the path of each request
+	BaseURL string
+}
+
+// RequestOption alters the request sent by Call
+type RequestOption func(o *requestOptions)
+
+type requestOptions struct {
+	query  url.Values
+	header http.Header
+	body   interface{}
+}
+
+// WithQuery adds a query parameter to the request
+func WithQuery(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.query.Add(key, value)
+	}
+}
+
+// WithHeader adds a header to the request
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		o.header.Add(key, value)
+	}
+}
+
+// WithJSONBody encodes the value in JSON format and sends it as the request's body
+func WithJSONBody(value interface{}) RequestOption {
+	return func(o *requestOptions) {
+		o.body = value
+	}
+}
+
+// Call sends a request to the API and decodes the response, in JSON format, into a value of type Response:
+//
+//	c := client.APIClient{Caller: &client.BaseClient{}, BaseURL: "https://api.example.com/v1"}
+//	user, err := client.Call[User](ctx, c, http.MethodGet, "/users/1", client.WithQuery("details", "true"))
+//
+// If the API responds with a non-2xx status code, Call returns an *HTTPError. If the response has no content,
+// Call returns Response's zero value.
+func Call[Response any](ctx context.Context, c APIClient, method, path string, options ...RequestOption) (response Response, err error) {
+	var req *http.Request
+	if req, err = c.newRequest(ctx, method, path, options...); err != nil {
+		return
+	}
+
+	caller := c.Caller
+	if caller == nil {
+		caller = &BaseClient{}
+	}
+
+	var resp *http.Response
+	if resp, err = caller.Do(req); err != nil {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if !isSuccess(resp) {
+		return response, newHTTPError(resp)
+	}
+
+	if resp.StatusCode == http.StatusNoContent {
+		return
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&response); err == io.EOF {
+		err = nil
+	} else if err != nil {
+		err = fmt.Errorf("decode: %w", err)
+	}
+	return
+}
+
+func (c APIClient) newRequest(ctx context.Context, method, path string, options ...RequestOption) (*http.Request, error) {
+	o := requestOptions{query: make(url.Values), header: make(http.Header)}
+	for _, option := range options {
+		option(&o)
+	}
+
+	target, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	target = target.JoinPath(path)
+	query := target.Query()
+	for key, values := range o.query {
+		query[key] = append(query[key], values...)
+	}
+	target.RawQuery = query.Encode()
+
+	var body io.Reader
+	if o.body != nil {
+		var content []byte
+		if content, err = json.Marshal(o.body); err != nil {
+			return nil, fmt.Errorf("encode: %w", err)
+		}
+		body = bytes.NewReader(content)
+		o.header.Set("Content-Type", "application/json")
+	}
+
+	var req *http.Request
+	if req, err = http.NewRequestWithContext(ctx, method, target.String(), body); err != nil {
+		return nil, err
+	}
+	for key, values := range o.header {
+		req.Header[key] = values
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	return req, nil
+}
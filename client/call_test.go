@@ -0,0 +1,72 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/clambin/cache"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCall(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/v1/users/1").ReturnJSON(http.StatusOK, testStruct{Name: "foo", Age: 42})
+	m.Expect(http.MethodPost, "/v1/users").WithBody(`{"name":"bar","age":12}`).ReturnJSON(http.StatusCreated, testStruct{Name: "bar", Age: 12})
+	m.Expect(http.MethodDelete, "/v1/users/1").Return(http.StatusNoContent, "")
+	m.Expect(http.MethodGet, "/v1/users/2").Return(http.StatusNotFound, "user not found")
+	m.Expect(http.MethodGet, "/v1/invalid").Return(http.StatusOK, "not json")
+	c := client.APIClient{Caller: m, BaseURL: "http://localhost/v1?key=secret"}
+	ctx := context.Background()
+
+	user, err := client.Call[testStruct](ctx, c, http.MethodGet, "/users/1", client.WithQuery("details", "true"), client.WithHeader("X-Foo", "bar"))
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Name: "foo", Age: 42}, user)
+
+	user, err = client.Call[testStruct](ctx, c, http.MethodPost, "users", client.WithJSONBody(testStruct{Name: "bar", Age: 12}))
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Name: "bar", Age: 12}, user)
+
+	_, err = client.Call[struct{}](ctx, c, http.MethodDelete, "/users/1")
+	require.NoError(t, err)
+
+	_, err = client.Call[testStruct](ctx, c, http.MethodGet, "/users/2")
+	var httpErr *client.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusNotFound, httpErr.StatusCode)
+	assert.Equal(t, "user not found", string(httpErr.Body))
+	assert.Equal(t, "GET http://localhost/v1/users/2?key=REDACTED: 404 Not Found", err.Error())
+	assert.NotContains(t, httpErr.URL, "secret")
+
+	_, err = client.Call[testStruct](ctx, c, http.MethodGet, "/invalid")
+	assert.Error(t, err)
+
+	m.AssertExpectations(t)
+
+	requests := m.Requests()
+	require.Len(t, requests, 5)
+	assert.Equal(t, "http://localhost/v1/users/1?details=true&key=secret", requests[0].URL)
+	assert.Equal(t, "bar", requests[0].Header.Get("X-Foo"))
+	assert.Equal(t, "application/json", requests[0].Header.Get("Accept"))
+	assert.Equal(t, "application/json", requests[1].Header.Get("Content-Type"))
+}
+
+func TestCall_Cacher(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/foo").ReturnJSON(http.StatusOK, testStruct{Name: "foo", Age: 42}).Once()
+	c := client.APIClient{
+		Caller:  &client.Cacher{Caller: m, Cache: cache.New[string, []byte](time.Minute, 0)},
+		BaseURL: "http://localhost",
+	}
+
+	for i := 0; i < 2; i++ {
+		user, err := client.Call[testStruct](context.Background(), c, http.MethodGet, "/foo")
+		require.NoError(t, err)
+		assert.Equal(t, "foo", user.Name)
+	}
+	m.AssertExpectations(t)
+}
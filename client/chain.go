@@ -0,0 +1,124 @@
+package client
+
+import (
+	"github.com/clambin/cache"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Middleware adds behaviour to a Caller, by wrapping it in another Caller
+type Middleware struct {
+	// Name of the middleware, as reported by Pipeline's Names method
+	Name string
+	// Wrap returns a Caller that adds the middleware's behaviour and sends the request to next
+	Wrap func(next Caller) Caller
+}
+
+// Pipeline is a Caller assembled by Build from a sequence of Middleware
+type Pipeline struct {
+	Caller
+	names []string
+}
+
+var _ Caller = &Pipeline{}
+
+// Build assembles a Caller pipeline. The first middleware is the outermost one, i.e. it receives the request first
+// and the response last. The last middleware sends the request to base. If base is nil, a BaseClient is used:
+//
+//	c := client.Build(&client.BaseClient{},
+//		client.WithCache(cacheEntries, time.Minute, 5*time.Minute),
+//		client.WithRetry(client.RetryOptions{MaxAttempts: 3}),
+//		client.WithInstrumentation("foo", client.Options{PrometheusMetrics: metrics}),
+//	)
+//
+// The order matters. In the above example, cached responses don't count as API calls in the metrics, and each retry
+// attempt is measured separately. A typical order is: logging, authentication, caching, retry, rate limiting,
+// instrumentation.
+func Build(base Caller, middleware ...Middleware) *Pipeline {
+	if base == nil {
+		base = &BaseClient{}
+	}
+	p := &Pipeline{Caller: base}
+	for index := len(middleware) - 1; index >= 0; index-- {
+		p.Caller = middleware[index].Wrap(p.Caller)
+	}
+	for _, m := range middleware {
+		p.names = append(p.names, m.Name)
+	}
+	return p
+}
+
+// Names returns the names of the Pipeline's middleware, from outermost to innermost
+func (p *Pipeline) Names() []string {
+	return append([]string{}, p.names...)
+}
+
+// String returns a description of the Pipeline, e.g. "cache -> retry -> instrumentation"
+func (p *Pipeline) String() string {
+	return strings.Join(p.names, " -> ")
+}
+
+// WithInstrumentation records performance metrics of each call, like InstrumentedClient
+func WithInstrumentation(application string, options Options) Middleware {
+	return Middleware{
+		Name: "instrumentation",
+		Wrap: func(next Caller) Caller {
+			return &instrumentedCaller{next: next, options: options, application: application}
+		},
+	}
+}
+
+type instrumentedCaller struct {
+	next        Caller
+	options     Options
+	application string
+}
+
+func (c *instrumentedCaller) Do(req *http.Request) (*http.Response, error) {
+	return c.options.call(c.next, c.application, req)
+}
+
+// WithCache caches responses as determined by cacheEntries, like Cacher
+func WithCache(cacheEntries []CacheTableEntry, cacheExpiry, cacheCleanup time.Duration) Middleware {
+	return Middleware{
+		Name: "cache",
+		Wrap: func(next Caller) Caller {
+			return &Cacher{
+				Caller: next,
+				Table:  CacheTable{Table: cacheEntries},
+				Cache:  cache.New[string, []byte](cacheExpiry, cacheCleanup),
+			}
+		},
+	}
+}
+
+// WithRetry retries failed calls, like Retrier
+func WithRetry(options RetryOptions) Middleware {
+	return Middleware{
+		Name: "retry",
+		Wrap: func(next Caller) Caller {
+			return &Retrier{Caller: next, Options: options}
+		},
+	}
+}
+
+// WithRateLimit limits the rate of calls, like RateLimiter
+func WithRateLimit(limit float64, burst int) Middleware {
+	return Middleware{
+		Name: "ratelimit",
+		Wrap: func(next Caller) Caller {
+			return &RateLimiter{Caller: next, Limit: limit, Burst: burst}
+		},
+	}
+}
+
+// WithStatusCheck converts non-2xx responses into an *HTTPError, like StatusChecker
+func WithStatusCheck() Middleware {
+	return Middleware{
+		Name: "statuscheck",
+		Wrap: func(next Caller) Caller {
+			return &StatusChecker{Caller: next}
+		},
+	}
+}
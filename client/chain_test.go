@@ -0,0 +1,74 @@
+package client_test
+
+import (
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBuild(t *testing.T) {
+	s := stubserver.New(
+		stubserver.Route{Path: "/foo", JSON: testStruct{Name: "foo", Age: 42}},
+		stubserver.Route{Path: "/flaky", JSON: testStruct{Name: "flaky", Age: 1}, FailFirst: 1},
+	)
+	defer s.Close()
+	metrics := client.NewMetrics("chain", "")
+
+	var order []string
+	trace := func(name string) client.Middleware {
+		return client.Middleware{Name: name, Wrap: func(next client.Caller) client.Caller {
+			return callerFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.Do(req)
+			})
+		}}
+	}
+
+	c := client.Build(nil,
+		trace("outer"),
+		client.WithCache([]client.CacheTableEntry{{Endpoint: "/foo"}}, time.Minute, 0),
+		client.WithRetry(client.RetryOptions{Backoff: time.Millisecond}),
+		client.WithRateLimit(1000, 10),
+		client.WithStatusCheck(),
+		client.WithInstrumentation("foo", client.Options{PrometheusMetrics: metrics}),
+		trace("inner"),
+	)
+	assert.Equal(t, []string{"outer", "cache", "retry", "ratelimit", "statuscheck", "instrumentation", "inner"}, c.Names())
+	assert.Equal(t, "outer -> cache -> retry -> ratelimit -> statuscheck -> instrumentation -> inner", c.String())
+
+	for i := 0; i < 2; i++ {
+		response, err := doCall(c, s.URL+"/foo")
+		require.NoError(t, err)
+		assert.Equal(t, "foo", response.Name)
+	}
+	assert.Equal(t, []string{"outer", "inner", "outer"}, order)
+	s.AssertCalls(t, http.MethodGet, "/foo", 1)
+
+	response, err := doCall(c, s.URL+"/flaky")
+	require.NoError(t, err)
+	assert.Equal(t, "flaky", response.Name)
+	s.AssertCalls(t, http.MethodGet, "/flaky", 2)
+
+	// client errors returned by the status check aren't retried
+	_, err = doCall(c, s.URL+"/missing")
+	assert.ErrorIs(t, err, client.ErrClientError)
+	s.AssertCalls(t, http.MethodGet, "/missing", 1)
+
+	latency, err := tools.Collect(metrics.Latency)
+	require.NoError(t, err)
+	summary, err := latency.Summary("chain_api_latency", prometheus.Labels{"application": "foo", "endpoint": "/flaky", "method": http.MethodGet})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), summary.GetSampleCount())
+}
+
+type callerFunc func(req *http.Request) (*http.Response, error)
+
+func (f callerFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
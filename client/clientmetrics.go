@@ -3,13 +3,18 @@ package client
 import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"net/http"
+	"time"
 )
 
 // Metrics contains Prometheus metrics to capture during API calls. Each metric is expected to have two labels:
 // the first will contain the application issuing the request. The second will contain the endpoint (i.e. Path) of the request.
+//
+// Calls that time out are counted as errors. If Timeouts is set, they are counted in Timeouts as well.
 type Metrics struct {
-	Latency *prometheus.SummaryVec // measures latency of an API call
-	Errors  *prometheus.CounterVec // measures any errors returned by an API call
+	Latency  *prometheus.SummaryVec // measures latency of an API call
+	Errors   *prometheus.CounterVec // measures any errors returned by an API call
+	Timeouts *prometheus.CounterVec // measures any API calls that timed out. These are also counted in Errors
 }
 
 // NewMetrics creates a standard set of Prometheus metrics to capture during API calls.
@@ -17,16 +22,20 @@ func NewMetrics(namespace, subsystem string) Metrics {
 	return Metrics{
 		Latency: promauto.NewSummaryVec(prometheus.SummaryOpts{
 			Name: prometheus.BuildFQName(namespace, subsystem, "api_latency"),
-			Help: "Latency of Reporter API calls",
+			Help: "Latency of API calls",
 		}, []string{"application", "endpoint", "method"}),
 		Errors: promauto.NewCounterVec(prometheus.CounterOpts{
 			Name: prometheus.BuildFQName(namespace, subsystem, "api_errors_total"),
-			Help: "Number of failed Reporter API calls",
+			Help: "Number of failed API calls",
+		}, []string{"application", "endpoint", "method"}),
+		Timeouts: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_timeouts_total"),
+			Help: "Number of API calls that timed out",
 		}, []string{"application", "endpoint", "method"}),
 	}
 }
 
-// ReportErrors measures any API client call failures:
+// ReportErrors measures any API client call failures. If Timeouts is set, timeouts are also counted separately:
 //
 //	err := callAPI(server, endpoint)
 //	pm.ReportErrors(err)
@@ -39,6 +48,13 @@ func (pm *Metrics) ReportErrors(err error, labelValues ...string) {
 	if err != nil {
 		value = 1.0
 	}
+	if pm.Timeouts != nil {
+		var timeout float64
+		if IsTimeout(err) {
+			timeout = 1.0
+		}
+		pm.Timeouts.WithLabelValues(labelValues...).Add(timeout)
+	}
 	pm.Errors.WithLabelValues(labelValues...).Add(value)
 }
 
@@ -56,3 +72,181 @@ func (pm *Metrics) MakeLatencyTimer(labelValues ...string) (timer *prometheus.Ti
 	}
 	return
 }
+
+// TokenMetrics contains Prometheus metrics to capture when a BearerTokenCaller refreshes its access token.
+// Refreshes is expected to have two labels: the application and the result ("success" or "failure").
+// RefreshLatency is expected to have one label: the application.
+type TokenMetrics struct {
+	Refreshes      *prometheus.CounterVec // counts token refreshes
+	RefreshLatency *prometheus.SummaryVec // measures the duration of token refreshes
+}
+
+// NewTokenMetrics creates a standard set of Prometheus metrics to capture token refreshes.
+func NewTokenMetrics(namespace, subsystem string) TokenMetrics {
+	return TokenMetrics{
+		Refreshes: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "token_refreshes_total"),
+			Help: "Number of access token refreshes",
+		}, []string{"application", "result"}),
+		RefreshLatency: promauto.NewSummaryVec(prometheus.SummaryOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "token_refresh_duration_seconds"),
+			Help: "Duration of access token refreshes",
+		}, []string{"application"}),
+	}
+}
+
+func (tm TokenMetrics) reportRefresh(application string, duration time.Duration, err error) {
+	if tm.Refreshes != nil {
+		result := "success"
+		if err != nil {
+			result = "failure"
+		}
+		tm.Refreshes.WithLabelValues(application, result).Inc()
+	}
+	if tm.RefreshLatency != nil {
+		tm.RefreshLatency.WithLabelValues(application).Observe(duration.Seconds())
+	}
+}
+
+// HedgeMetrics contains Prometheus metrics to capture when a Hedger sends hedged requests. Each metric is expected
+// to have three labels: the application, the endpoint (i.e. Path) and the method of the request.
+type HedgeMetrics struct {
+	Hedges *prometheus.CounterVec // counts hedged requests
+	Wins   *prometheus.CounterVec // counts hedged requests that responded before the original request
+}
+
+// NewHedgeMetrics creates a standard set of Prometheus metrics to capture hedged requests.
+func NewHedgeMetrics(namespace, subsystem string) HedgeMetrics {
+	return HedgeMetrics{
+		Hedges: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_hedges_total"),
+			Help: "Number of hedged API calls",
+		}, []string{"application", "endpoint", "method"}),
+		Wins: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_hedge_wins_total"),
+			Help: "Number of hedged API calls that responded before the original call",
+		}, []string{"application", "endpoint", "method"}),
+	}
+}
+
+func (hm HedgeMetrics) reportHedge(application string, req *http.Request) {
+	if hm.Hedges != nil {
+		hm.Hedges.WithLabelValues(application, req.URL.Path, req.Method).Inc()
+	}
+}
+
+func (hm HedgeMetrics) reportWin(application string, req *http.Request) {
+	if hm.Wins != nil {
+		hm.Wins.WithLabelValues(application, req.URL.Path, req.Method).Inc()
+	}
+}
+
+// LoadBalancerMetrics contains Prometheus metrics to capture the calls a LoadBalancer sends to each target.
+// Each metric is expected to have two labels: the application and the target (i.e. the target's URL).
+type LoadBalancerMetrics struct {
+	Latency   *prometheus.SummaryVec // measures latency of calls to a target
+	Errors    *prometheus.CounterVec // counts failed calls to a target
+	Ejections *prometheus.CounterVec // counts how often a target was ejected
+	Healthy   *prometheus.GaugeVec   // is 1 if a target is healthy, 0 if it is ejected
+}
+
+// NewLoadBalancerMetrics creates a standard set of Prometheus metrics to capture calls to a LoadBalancer's targets.
+func NewLoadBalancerMetrics(namespace, subsystem string) LoadBalancerMetrics {
+	return LoadBalancerMetrics{
+		Latency: promauto.NewSummaryVec(prometheus.SummaryOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_target_latency_seconds"),
+			Help: "Latency of API calls per target",
+		}, []string{"application", "target"}),
+		Errors: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_target_errors_total"),
+			Help: "Number of failed API calls per target",
+		}, []string{"application", "target"}),
+		Ejections: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_target_ejections_total"),
+			Help: "Number of times a target was ejected",
+		}, []string{"application", "target"}),
+		Healthy: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_target_healthy"),
+			Help: "Health of each target (1: healthy, 0: ejected)",
+		}, []string{"application", "target"}),
+	}
+}
+
+func (lm LoadBalancerMetrics) report(application, target string, duration time.Duration, failed bool) {
+	if lm.Latency != nil {
+		lm.Latency.WithLabelValues(application, target).Observe(duration.Seconds())
+	}
+	if lm.Errors != nil {
+		var value float64
+		if failed {
+			value = 1.0
+		}
+		lm.Errors.WithLabelValues(application, target).Add(value)
+	}
+}
+
+func (lm LoadBalancerMetrics) reportEjection(application, target string) {
+	if lm.Ejections != nil {
+		lm.Ejections.WithLabelValues(application, target).Inc()
+	}
+	lm.reportHealthy(application, target, false)
+}
+
+func (lm LoadBalancerMetrics) reportHealthy(application, target string, healthy bool) {
+	if lm.Healthy != nil {
+		var value float64
+		if healthy {
+			value = 1.0
+		}
+		lm.Healthy.WithLabelValues(application, target).Set(value)
+	}
+}
+
+// BulkheadMetrics contains Prometheus metrics to capture the calls limited by a Bulkhead or AdaptiveLimiter. Each metric is expected
+// to have two labels: the application and the name of the group.
+type BulkheadMetrics struct {
+	Limit      *prometheus.GaugeVec   // maximum number of calls in progress
+	Active     *prometheus.GaugeVec   // number of calls in progress
+	Queued     *prometheus.GaugeVec   // number of calls waiting in the queue
+	Rejections *prometheus.CounterVec // counts rejected calls
+}
+
+// NewBulkheadMetrics creates a standard set of Prometheus metrics to capture the calls limited by a Bulkhead.
+func NewBulkheadMetrics(namespace, subsystem string) BulkheadMetrics {
+	return BulkheadMetrics{
+		Limit: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_bulkhead_limit"),
+			Help: "Maximum number of API calls in progress",
+		}, []string{"application", "group"}),
+		Active: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_bulkhead_active"),
+			Help: "Number of API calls in progress",
+		}, []string{"application", "group"}),
+		Queued: promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_bulkhead_queued"),
+			Help: "Number of API calls waiting to be sent",
+		}, []string{"application", "group"}),
+		Rejections: promauto.NewCounterVec(prometheus.CounterOpts{
+			Name: prometheus.BuildFQName(namespace, subsystem, "api_bulkhead_rejections_total"),
+			Help: "Number of API calls rejected by the bulkhead",
+		}, []string{"application", "group"}),
+	}
+}
+
+func (bm BulkheadMetrics) report(application, group string, limit, active, queued int) {
+	if bm.Limit != nil {
+		bm.Limit.WithLabelValues(application, group).Set(float64(limit))
+	}
+	if bm.Active != nil {
+		bm.Active.WithLabelValues(application, group).Set(float64(active))
+	}
+	if bm.Queued != nil {
+		bm.Queued.WithLabelValues(application, group).Set(float64(queued))
+	}
+}
+
+func (bm BulkheadMetrics) reportRejection(application, group string) {
+	if bm.Rejections != nil {
+		bm.Rejections.WithLabelValues(application, group).Inc()
+	}
+}
@@ -63,6 +63,75 @@ func TestClientMetrics_ReportErrors(t *testing.T) {
 	assert.Equal(t, 1.0, tools.MetricValue(m).GetCounter().GetValue())
 }
 
+func TestNewMetrics_Lint(t *testing.T) {
+	cfg := client.NewMetrics("lint", "")
+	cfg.ReportErrors(nil, "foo", "/bar", http.MethodGet)
+	cfg.MakeLatencyTimer("foo", "/bar", http.MethodGet).ObserveDuration()
+
+	// api_latency predates the unit-suffix rule. renaming it would break existing dashboards.
+	findings, err := tools.Lint(cfg.Latency, tools.LintOptions{IgnoreRules: []string{tools.RuleUnitSuffix}})
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+
+	for _, collector := range []prometheus.Collector{cfg.Errors, cfg.Timeouts} {
+		findings, err = tools.Lint(collector, tools.LintOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	}
+}
+
+func TestNewTokenMetrics_Lint(t *testing.T) {
+	cfg := client.NewTokenMetrics("lint", "")
+	cfg.Refreshes.WithLabelValues("foo", "success").Inc()
+	cfg.RefreshLatency.WithLabelValues("foo").Observe(0.1)
+
+	for _, collector := range []prometheus.Collector{cfg.Refreshes, cfg.RefreshLatency} {
+		findings, err := tools.Lint(collector, tools.LintOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	}
+}
+
+func TestNewHedgeMetrics_Lint(t *testing.T) {
+	cfg := client.NewHedgeMetrics("lint", "")
+	cfg.Hedges.WithLabelValues("foo", "/bar", http.MethodGet).Inc()
+	cfg.Wins.WithLabelValues("foo", "/bar", http.MethodGet).Inc()
+
+	for _, collector := range []prometheus.Collector{cfg.Hedges, cfg.Wins} {
+		findings, err := tools.Lint(collector, tools.LintOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	}
+}
+
+func TestNewLoadBalancerMetrics_Lint(t *testing.T) {
+	cfg := client.NewLoadBalancerMetrics("lint", "")
+	cfg.Latency.WithLabelValues("foo", "http://localhost:8080").Observe(0.1)
+	cfg.Errors.WithLabelValues("foo", "http://localhost:8080").Inc()
+	cfg.Ejections.WithLabelValues("foo", "http://localhost:8080").Inc()
+	cfg.Healthy.WithLabelValues("foo", "http://localhost:8080").Set(1)
+
+	for _, collector := range []prometheus.Collector{cfg.Latency, cfg.Errors, cfg.Ejections, cfg.Healthy} {
+		findings, err := tools.Lint(collector, tools.LintOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	}
+}
+
+func TestNewBulkheadMetrics_Lint(t *testing.T) {
+	cfg := client.NewBulkheadMetrics("lint", "")
+	cfg.Limit.WithLabelValues("foo", "bar").Set(1)
+	cfg.Active.WithLabelValues("foo", "bar").Set(1)
+	cfg.Queued.WithLabelValues("foo", "bar").Set(1)
+	cfg.Rejections.WithLabelValues("foo", "bar").Inc()
+
+	for _, collector := range []prometheus.Collector{cfg.Limit, cfg.Active, cfg.Queued, cfg.Rejections} {
+		findings, err := tools.Lint(collector, tools.LintOptions{})
+		require.NoError(t, err)
+		assert.Empty(t, findings)
+	}
+}
+
 func TestClientMetrics_Nil(t *testing.T) {
 	cfg := client.Metrics{}
 
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"strings"
+	"sync"
+)
+
+// Deduplicator collapses identical GET and HEAD requests that are in progress at the same time into a single call.
+// The other requests wait for that call to complete and receive a copy of its response:
+//
+//	c := &client.Deduplicator{Caller: &client.BaseClient{}}
+//
+// Requests are identical if they have the same method, URL and KeyHeaders, and are made with the same
+// credentials (see WithIdentity). Contrary to a Cacher, responses are only shared while the call is in progress.
+//
+// The response is only read into memory if other requests are waiting for it. Otherwise, its body is streamed as usual.
+// If the body is larger than MaxBodySize, or if the call fails because the first request's context was cancelled,
+// the other requests are sent separately. Requests with other methods, or with a body, are sent as is.
+type Deduplicator struct {
+	Caller
+	// KeyHeaders contains the headers whose values must match for requests to be identical.
+	// If nil, DefaultKeyHeaders is used
+	KeyHeaders []string
+	// MaxBodySize is the maximum size of a response body that is shared with waiting requests.
+	// If zero, DefaultMaxDedupBodySize is used
+	MaxBodySize int64
+	calls       map[string]*dedupCall
+	lock        sync.Mutex
+}
+
+var _ Caller = &Deduplicator{}
+
+// DefaultKeyHeaders contains the headers whose values must match for requests to be identical, if
+// a Deduplicator's KeyHeaders is nil
+var DefaultKeyHeaders = []string{"Accept", "Accept-Encoding", "Accept-Language"}
+
+// DefaultMaxDedupBodySize is the maximum size of a shared response body, if a Deduplicator's MaxBodySize is zero
+const DefaultMaxDedupBodySize = 1 << 20
+
+// dedupCall is a call in progress. Once done is closed, response holds the response, in the format produced
+// by httputil.DumpResponse. If response is nil, the response wasn't shared.
+type dedupCall struct {
+	done     chan struct{}
+	waiting  int
+	response []byte
+	err      error
+}
+
+// Do sends the request, or waits for an identical request in progress to complete
+func (d *Deduplicator) Do(req *http.Request) (resp *http.Response, err error) {
+	if (req.Method != http.MethodGet && req.Method != http.MethodHead) || (req.Body != nil && req.Body != http.NoBody) {
+		return d.Caller.Do(req)
+	}
+
+	key := d.key(req)
+	d.lock.Lock()
+	if d.calls == nil {
+		d.calls = make(map[string]*dedupCall)
+	}
+	if call, found := d.calls[key]; found {
+		call.waiting++
+		d.lock.Unlock()
+		return d.wait(req, call)
+	}
+	call := &dedupCall{done: make(chan struct{})}
+	d.calls[key] = call
+	d.lock.Unlock()
+
+	defer func() {
+		d.remove(key, call)
+		close(call.done)
+	}()
+
+	if resp, err = d.Caller.Do(req); err != nil {
+		call.err = err
+		return
+	}
+	// once the call is removed, no more requests can start waiting for it
+	if waiting := d.remove(key, call); waiting == 0 {
+		return
+	}
+	if call.response, err = d.share(resp, req.Method != http.MethodHead); err != nil {
+		call.err = err
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return
+}
+
+// remove stops other requests from waiting for the call, and returns the number of requests waiting for it
+func (d *Deduplicator) remove(key string, call *dedupCall) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if d.calls[key] == call {
+		delete(d.calls, key)
+	}
+	return call.waiting
+}
+
+// share reads the response into memory, so it can be shared with the waiting requests. If the body is larger than
+// MaxBodySize, the response isn't shared: share returns nil and the response's body can still be read in full.
+func (d *Deduplicator) share(resp *http.Response, withBody bool) ([]byte, error) {
+	if !withBody {
+		return httputil.DumpResponse(resp, false)
+	}
+	maxBodySize := d.MaxBodySize
+	if maxBodySize == 0 {
+		maxBodySize = DefaultMaxDedupBodySize
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxBodySize {
+		resp.Body = &struct {
+			io.Reader
+			io.Closer
+		}{Reader: io.MultiReader(bytes.NewReader(body), resp.Body), Closer: resp.Body}
+		return nil, nil
+	}
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	// DumpResponse reads the in-memory body and replaces it with a copy
+	return httputil.DumpResponse(resp, true)
+}
+
+func (d *Deduplicator) wait(req *http.Request, call *dedupCall) (*http.Response, error) {
+	select {
+	case <-call.done:
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+	if call.err != nil {
+		if errors.Is(call.err, context.Canceled) || errors.Is(call.err, context.DeadlineExceeded) {
+			// the call failed because of the first request's context: send this request separately
+			return d.Caller.Do(req)
+		}
+		return nil, call.err
+	}
+	if call.response == nil {
+		// the response wasn't shared (e.g. its body was too large): send this request separately
+		return d.Caller.Do(req)
+	}
+	return cachedResponse(call.response, req)
+}
+
+func (d *Deduplicator) key(req *http.Request) string {
+	keyHeaders := d.KeyHeaders
+	if keyHeaders == nil {
+		keyHeaders = DefaultKeyHeaders
+	}
+	var key strings.Builder
+	key.WriteString(req.Method + " " + req.URL.String() + "\n")
+	for _, header := range keyHeaders {
+		key.WriteString(header + ":" + strings.Join(req.Header.Values(header), ",") + "\n")
+	}
+	key.WriteString(requestIdentity(req))
+	return key.String()
+}
+
+// WithDeduplication collapses identical GET and HEAD requests in progress into a single call, like Deduplicator
+func WithDeduplication(keyHeaders ...string) Middleware {
+	return Middleware{
+		Name: "deduplication",
+		Wrap: func(next Caller) Caller {
+			return &Deduplicator{Caller: next, KeyHeaders: keyHeaders}
+		},
+	}
+}
@@ -0,0 +1,168 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeduplicator(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "hello", Latency: 100 * time.Millisecond})
+	defer s.Close()
+	c := &client.Deduplicator{Caller: &client.BaseClient{}}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, body, err := get(c, http.MethodGet, s.URL+"/foo")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, "hello", body)
+		}()
+	}
+	wg.Wait()
+	s.AssertCalls(t, http.MethodGet, "/foo", 1)
+
+	// once the call has completed, the response is no longer shared
+	_, _, err := get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+	s.AssertCalls(t, http.MethodGet, "/foo", 2)
+}
+
+func TestDeduplicator_Key(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "hello", Latency: 100 * time.Millisecond})
+	defer s.Close()
+	c := client.Build(nil, client.WithDeduplication())
+
+	requests := []func() *http.Request{
+		func() *http.Request {
+			req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo", nil)
+			req.Header.Set("Accept", "application/json")
+			return req
+		},
+		func() *http.Request {
+			req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo", nil)
+			req.Header.Set("Accept", "text/plain")
+			return req
+		},
+		func() *http.Request {
+			req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo", nil)
+			req.Header.Set("Accept", "text/plain")
+			req.Header.Set("Authorization", "Bearer 123")
+			return req
+		},
+		func() *http.Request {
+			req, _ := http.NewRequest(http.MethodPost, s.URL+"/foo", nil)
+			return req
+		},
+		func() *http.Request {
+			req, _ := http.NewRequest(http.MethodPost, s.URL+"/foo", nil)
+			return req
+		},
+		func() *http.Request {
+			req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo", strings.NewReader("body"))
+			return req
+		},
+	}
+
+	var wg sync.WaitGroup
+	for _, newRequest := range requests {
+		wg.Add(1)
+		go func(req *http.Request) {
+			defer wg.Done()
+			resp, err := c.Do(req)
+			require.NoError(t, err)
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}(newRequest())
+	}
+	wg.Wait()
+	s.AssertCalls(t, http.MethodGet, "/foo", 4)
+	s.AssertCalls(t, http.MethodPost, "/foo", 2)
+}
+
+func TestDeduplicator_Cancel(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "hello", Latency: 100 * time.Millisecond})
+	defer s.Close()
+	c := &client.Deduplicator{Caller: &client.BaseClient{}}
+
+	// the first request is cancelled: the second request is sent separately
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	first := make(chan error)
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, s.URL+"/foo", nil)
+		_, err := c.Do(req)
+		first <- err
+	}()
+	require.Eventually(t, func() bool { return s.TotalCalls() == 1 }, time.Second, time.Millisecond)
+
+	_, body, err := get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", body)
+	assert.True(t, errors.Is(<-first, context.DeadlineExceeded))
+	s.AssertCalls(t, http.MethodGet, "/foo", 2)
+}
+
+func TestDeduplicator_Streaming(t *testing.T) {
+	body, w := io.Pipe()
+	defer func() { _ = w.Close() }()
+	c := &client.Deduplicator{Caller: callerFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: body, Request: req}, nil
+	})}
+
+	// no other requests are waiting: the response is returned before its body has been sent
+	done := make(chan *http.Response)
+	go func() {
+		req, _ := http.NewRequest(http.MethodGet, "http://localhost/foo", nil)
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		done <- resp
+	}()
+	var resp *http.Response
+	select {
+	case resp = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("response was buffered")
+	}
+
+	go func() {
+		_, _ = w.Write([]byte("hello"))
+		_ = w.Close()
+	}()
+	content, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(content))
+}
+
+func TestDeduplicator_MaxBodySize(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "hello world", Latency: 100 * time.Millisecond})
+	defer s.Close()
+	c := &client.Deduplicator{Caller: &client.BaseClient{}, MaxBodySize: 5}
+
+	// the body is too large to be shared: each request is sent separately
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status, body, err := get(c, http.MethodGet, s.URL+"/foo")
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, status)
+			assert.Equal(t, "hello world", body)
+		}()
+	}
+	wg.Wait()
+	s.AssertCalls(t, http.MethodGet, "/foo", 5)
+}
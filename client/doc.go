@@ -39,7 +39,7 @@ Cacher caches responses to HTTP requests:
 
 This creates a Cacher that will cache the response of called to any request with Path '/foo', for up to 50 msec.
 
-Note: NewCacher will create a Caller that also generates Prometheus metrics by chaining the request to an InstrumentedClient.
+Note: NewCacher will create a Caller that also generates Prometheus metrics, using WithInstrumentation (see Build).
 To avoid this, create the Cacher object directly:
 
 	c := &Cacher{
@@ -47,5 +47,117 @@ To avoid this, create the Cacher object directly:
 		Table: CacheTable{Table: cacheEntries},
 		Cache: cache.New[string, []byte](cacheExpiry, cacheCleanup),
 
+Call sends a request through any Caller chain and decodes the JSON response into a typed value:
+
+	c := client.APIClient{Caller: cacher, BaseURL: "https://api.example.com/v1"}
+	user, err := client.Call[User](ctx, c, http.MethodGet, "/users/1", client.WithQuery("details", "true"))
+
+Non-2xx responses are returned as an *HTTPError, which holds the request's method and URL, and the response's
+status code, headers and (truncated) body. StatusChecker does the same for any Caller chain. Use errors.Is
+with ErrClientError or ErrServerError to classify the error.
+
+To instrument or cache the calls made by libraries that accept an *http.Client, use the http.RoundTripper
+equivalents InstrumentedRoundTripper and NewCachingRoundTripper. CallerRoundTripper turns any Caller chain into
+an http.RoundTripper, and RoundTripperCaller does the reverse:
+
+	httpClient := &http.Client{Transport: &client.CallerRoundTripper{Caller: cacher}}
+
+Recorder records API calls to a cassette file, so tests can replay them later without network access:
+
+	c := &client.Recorder{Caller: &client.BaseClient{}, Mode: client.RecordMode, Cassette: "testdata/api.json"}
+
+Once recorded, set Mode to ReplayMode to serve the responses from the cassette.
+
+Build assembles a Caller chain from a sequence of Middleware. The first middleware receives the request first:
+
+	c := client.Build(&client.BaseClient{},
+		client.WithCache(cacheEntries, time.Minute, 5*time.Minute),
+		client.WithRetry(client.RetryOptions{MaxAttempts: 3}),
+		client.WithRateLimit(10, 5),
+		client.WithInstrumentation("foo", client.Options{PrometheusMetrics: metrics}),
+	)
+
+Retrier retries failed calls with exponential backoff, honouring Retry-After headers. RateLimiter limits the
+number of calls per second. Both can also be used directly.
+
+HeaderCaller adds static headers (e.g. an API key) to each request. BearerTokenCaller adds an access token from a
+TokenSource, refreshing it before it expires. ClientCredentials is a TokenSource for the OAuth2 client credentials flow:
+
+	c := client.Build(nil,
+		client.WithBearerToken(&client.ClientCredentials{TokenURL: tokenURL, ClientID: id, ClientSecret: secret},
+			"foo", client.NewTokenMetrics("foo", "")),
+		client.WithCache(cacheEntries, time.Minute, 5*time.Minute),
+	)
+
+Cacher includes the request's credentials in its cache key, so responses are never shared across credentials.
+
+Logger logs each call with log/slog, including whether the response was cached and how many attempts it took.
+Sensitive headers and query parameters are redacted:
+
+	c := client.Build(nil,
+		client.WithLogging(slog.Default(), client.LogOptions{Sample: 10}),
+		client.WithCache(cacheEntries, time.Minute, 5*time.Minute),
+		client.WithRetry(client.RetryOptions{}),
+	)
+
+TimeoutCaller applies a timeout to each request, based on its endpoint. Combined with a Retrier's Budget, this
+limits both the duration of each attempt and the total duration of the call:
+
+	c := client.Build(nil,
+		client.WithInstrumentation("foo", client.Options{PrometheusMetrics: metrics}),
+		client.WithRetry(client.RetryOptions{MaxAttempts: 3, Budget: 10 * time.Second}),
+		client.WithTimeouts([]client.TimeoutTableEntry{{Endpoint: "/reports", Timeout: 5 * time.Second}}, time.Second),
+	)
+
+Calls that time out are counted as errors. If the Metrics' Timeouts counter is set, they are counted there as well.
+
+Hedger protects slow GET and HEAD endpoints against tail latency. If a request takes longer than the latency
+measured for its endpoint (e.g. the 95th percentile), Hedger sends a second request and returns the first successful
+response. The latency summary needs an objective for the percentile. The summary created by NewMetrics has none:
+
+	metrics := client.Metrics{Latency: promauto.NewSummaryVec(prometheus.SummaryOpts{
+		Name:       "foo_api_latency",
+		Help:       "Latency of API calls",
+		Objectives: map[float64]float64{0.95: 0.01},
+	}, []string{"application", "endpoint", "method"})}
+	c := client.Build(nil,
+		client.WithHedging(client.HedgeOptions{Application: "foo", LatencyMetrics: metrics, Percentile: 0.95}),
+		client.WithInstrumentation("foo", client.Options{PrometheusMetrics: metrics}),
+	)
+
+LoadBalancer spreads requests across several replicas of a service, using a RoundRobin, LeastInFlight or Weighted
+strategy. Targets that fail several consecutive calls are ejected for a while:
+
+	c := client.Build(nil, client.WithLoadBalancing(client.LoadBalancerOptions{
+		Targets:     []client.Target{{URL: "http://10.0.0.1:8080"}, {URL: "http://10.0.0.2:8080"}},
+		Strategy:    client.LeastInFlight,
+		Application: "foo",
+		Metrics:     client.NewLoadBalancerMetrics("foo", ""),
+	}))
+
+Bulkhead limits the number of concurrent calls to groups of endpoints. Calls beyond the limit wait in a bounded
+queue. If the queue is full, or a call waits too long, the call fails with a *BulkheadError:
+
+	c := client.Build(nil, client.WithBulkhead(client.BulkheadOptions{
+		Groups: []client.BulkheadGroup{{Name: "reports", Endpoint: "/reports", MaxConcurrent: 2, MaxQueued: 10}},
+	}))
+
+AdaptiveLimiter limits the number of concurrent calls too, but adjusts the limit to the latency and errors it observes:
+the limit shrinks when calls fail or are slow, and grows while calls succeed. The current limit is exported in the
+BulkheadMetrics' Limit gauge:
+
+	c := client.Build(nil, client.WithAdaptiveLimit(client.AdaptiveLimiterOptions{
+		LatencyThreshold: 500 * time.Millisecond,
+		Application:      "foo",
+		Metrics:          client.NewBulkheadMetrics("foo", ""),
+	}))
+
+Deduplicator collapses identical GET and HEAD requests that are in progress at the same time into a single call,
+whether or not their responses are cached:
+
+	c := client.Build(nil, client.WithDeduplication(), client.WithInstrumentation("foo", options))
+
+A response is only read into memory if other requests are waiting for it, and only if its body is no larger than
+the Deduplicator's MaxBodySize. Otherwise, the waiting requests are sent separately.
 */
 package client
@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	pcg "github.com/prometheus/client_model/go"
+	"math"
+	"net/http"
+	"time"
+)
+
+// Hedger protects latency-sensitive GET and HEAD requests against slow responses. If a request hasn't completed
+// within a delay, Hedger sends a second (hedged) request and returns whichever response arrives first.
+// The other request is cancelled. Requests with other methods are sent as is.
+//
+// The delay is the Percentile of the latency already measured for the request's endpoint by InstrumentedClient
+// (or WithInstrumentation). This requires a latency summary with a matching objective, and with the labels
+// application, endpoint and method:
+//
+//	metrics := client.Metrics{Latency: promauto.NewSummaryVec(prometheus.SummaryOpts{
+//		Name:       "foo_api_latency",
+//		Help:       "Latency of API calls",
+//		Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.01},
+//	}, []string{"application", "endpoint", "method"})}
+//	c := &client.Hedger{Caller: instrumentedClient, Options: client.HedgeOptions{
+//		Application:    "foo",
+//		LatencyMetrics: metrics,
+//		Percentile:     0.95,
+//	}}
+//
+// The summary created by NewMetrics has no objectives. If the summary has no matching quantile, or until enough
+// calls have been measured, the Delay option is used.
+//
+// The losing request is cancelled, so InstrumentedClient doesn't record it as an error, and its truncated latency
+// doesn't skew the delay. A response with a 5xx status code, or an error, is not considered a success. If the first response fails,
+// Hedger waits for the other one. If both fail, Hedger returns the last one.
+type Hedger struct {
+	Caller
+	Options HedgeOptions
+}
+
+var _ Caller = &Hedger{}
+
+// HedgeOptions contains options to alter Hedger behaviour
+type HedgeOptions struct {
+	// Application is the application label of the latency metric
+	Application string
+	// LatencyMetrics holds the latency metric used to determine the delay. Its Latency summary must have an objective
+	// for Percentile. Hedger only reads the summary: it doesn't create any series
+	LatencyMetrics Metrics
+	// Percentile of the latency at which the hedged request is sent. If zero, 0.95 is used
+	Percentile float64
+	// Delay is used when the latency metric can't determine the delay. If zero, DefaultHedgeDelay is used
+	Delay time.Duration
+	// AlternateHost is the host (and optional port) to send the hedged request to. If empty, the hedged request
+	// is sent to the same host
+	AlternateHost string
+	// Metrics records the hedged requests. If the metrics are nil, no metrics are recorded
+	Metrics HedgeMetrics
+}
+
+// DefaultHedgeDelay is the default delay before sending a hedged request, if no latency has been measured yet
+const DefaultHedgeDelay = 100 * time.Millisecond
+
+// minHedgeSamples is the number of calls that must be measured before the latency metric determines the delay
+const minHedgeSamples = 10
+
+type hedgeResult struct {
+	index int
+	resp  *http.Response
+	err   error
+}
+
+// Do sends the request, hedging it if it takes too long
+func (h *Hedger) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return h.Caller.Do(req)
+	}
+
+	results := make(chan hedgeResult, 2)
+	var cancels [2]context.CancelFunc
+	send := func(index int, r *http.Request) {
+		ctx, cancel := context.WithCancel(req.Context())
+		cancels[index] = cancel
+		go func() {
+			resp, err := h.Caller.Do(r.WithContext(ctx))
+			results <- hedgeResult{index: index, resp: resp, err: err}
+		}()
+	}
+
+	send(0, req)
+	inFlight := 1
+	timer := time.NewTimer(h.Options.delay(req))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			send(1, h.Options.hedgedRequest(req))
+			inFlight++
+			h.Options.Metrics.reportHedge(h.Options.Application, req)
+		case result := <-results:
+			inFlight--
+			if !isHedgeSuccess(result) && inFlight > 0 {
+				// wait for the other request
+				if result.resp != nil {
+					_ = result.resp.Body.Close()
+				}
+				cancels[result.index]()
+				continue
+			}
+			// cancel the other request and discard its response
+			for ; inFlight > 0; inFlight-- {
+				cancels[1-result.index]()
+				go func() {
+					if loser := <-results; loser.resp != nil {
+						_ = loser.resp.Body.Close()
+					}
+				}()
+			}
+			if result.index == 1 && isHedgeSuccess(result) {
+				h.Options.Metrics.reportWin(h.Options.Application, req)
+			}
+			if result.err != nil {
+				cancels[result.index]()
+				return nil, result.err
+			}
+			result.resp.Body = &cancelOnClose{ReadCloser: result.resp.Body, cancel: cancels[result.index]}
+			return result.resp, nil
+		}
+	}
+}
+
+func isHedgeSuccess(result hedgeResult) bool {
+	return result.err == nil && result.resp.StatusCode < 500
+}
+
+func (o HedgeOptions) hedgedRequest(req *http.Request) *http.Request {
+	r := req.Clone(req.Context())
+	if o.AlternateHost != "" {
+		r.URL.Host = o.AlternateHost
+		r.Host = ""
+	}
+	return r
+}
+
+// delay returns the delay before sending a hedged request for the request
+func (o HedgeOptions) delay(req *http.Request) time.Duration {
+	if latency := o.latency(req); latency > 0 {
+		return latency
+	}
+	if o.Delay > 0 {
+		return o.Delay
+	}
+	return DefaultHedgeDelay
+}
+
+// latency returns the measured latency of the request's endpoint at the Percentile. Returns zero if the metric has
+// no matching quantile, or if not enough calls have been measured.
+func (o HedgeOptions) latency(req *http.Request) time.Duration {
+	if o.LatencyMetrics.Latency == nil {
+		return 0
+	}
+	m := lookupMetric(o.LatencyMetrics.Latency, prometheus.Labels{
+		"application": o.Application,
+		"endpoint":    req.URL.Path,
+		"method":      req.Method,
+	})
+	if m == nil || m.GetSummary().GetSampleCount() < minHedgeSamples {
+		return 0
+	}
+
+	percentile := o.Percentile
+	if percentile == 0 {
+		percentile = 0.95
+	}
+	for _, quantile := range m.GetSummary().GetQuantile() {
+		if math.Abs(quantile.GetQuantile()-percentile) < 1e-6 && !math.IsNaN(quantile.GetValue()) {
+			return time.Duration(quantile.GetValue() * float64(time.Second))
+		}
+	}
+	return 0
+}
+
+// lookupMetric returns the collector's metric with the specified labels, or nil if the collector has no such metric.
+// Contrary to a vector's GetMetricWith, lookupMetric doesn't create the metric if it doesn't exist yet.
+func lookupMetric(collector prometheus.Collector, labels prometheus.Labels) *pcg.Metric {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+
+	var found *pcg.Metric
+	for metric := range ch {
+		var m pcg.Metric
+		if found == nil && metric.Write(&m) == nil && hasLabels(&m, labels) {
+			found = &m
+		}
+	}
+	return found
+}
+
+// hasLabels reports whether the metric has the specified labels
+func hasLabels(m *pcg.Metric, labels prometheus.Labels) bool {
+	var matched int
+	for _, label := range m.GetLabel() {
+		if value, ok := labels[label.GetName()]; ok {
+			if value != label.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
+
+// WithHedging hedges slow GET and HEAD requests, like Hedger
+func WithHedging(options HedgeOptions) Middleware {
+	return Middleware{
+		Name: "hedge",
+		Wrap: func(next Caller) Caller {
+			return &Hedger{Caller: next, Options: options}
+		},
+	}
+}
@@ -0,0 +1,239 @@
+package client_test
+
+import (
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedger(t *testing.T) {
+	var cancelled atomic.Bool
+	s := stubserver.New(
+		stubserver.Route{Path: "/slow", Handler: slowFirst(time.Second, &cancelled)},
+		stubserver.Route{Path: "/fast", Body: "hello"},
+	)
+	defer s.Close()
+	metrics := client.NewHedgeMetrics("hedger", "")
+	c := &client.Hedger{
+		Caller:  &client.BaseClient{},
+		Options: client.HedgeOptions{Application: "foo", Delay: 20 * time.Millisecond, Metrics: metrics},
+	}
+
+	start := time.Now()
+	status, body, err := get(c, http.MethodGet, s.URL+"/slow")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "hello", body)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	s.AssertCalls(t, http.MethodGet, "/slow", 2)
+	assert.Eventually(t, cancelled.Load, time.Second, 10*time.Millisecond)
+
+	_, _, err = get(c, http.MethodGet, s.URL+"/fast")
+	require.NoError(t, err)
+	s.AssertCalls(t, http.MethodGet, "/fast", 1)
+
+	labels := prometheus.Labels{"application": "foo", "endpoint": "/slow", "method": http.MethodGet}
+	m, err := tools.Collect(metrics.Hedges)
+	require.NoError(t, err)
+	value, err := m.Counter("hedger_api_hedges_total", labels)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+	m, err = tools.Collect(metrics.Wins)
+	require.NoError(t, err)
+	value, err = m.Counter("hedger_api_hedge_wins_total", labels)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+}
+
+func TestHedger_NotIdempotent(t *testing.T) {
+	var cancelled atomic.Bool
+	s := stubserver.New(stubserver.Route{Path: "/slow", Handler: slowFirst(100*time.Millisecond, &cancelled)})
+	defer s.Close()
+	c := &client.Hedger{Caller: &client.BaseClient{}, Options: client.HedgeOptions{Delay: 10 * time.Millisecond}}
+
+	status, _, err := get(c, http.MethodPost, s.URL+"/slow")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, status)
+	s.AssertCalls(t, http.MethodPost, "/slow", 1)
+	assert.False(t, cancelled.Load())
+}
+
+func TestHedger_AlternateHost(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "primary", Latency: time.Second})
+	defer s.Close()
+	alternate := stubserver.New(stubserver.Route{Path: "/foo", Body: "alternate"})
+	defer alternate.Close()
+	c := &client.Hedger{Caller: &client.BaseClient{}, Options: client.HedgeOptions{
+		Delay:         10 * time.Millisecond,
+		AlternateHost: strings.TrimPrefix(alternate.URL, "http://"),
+	}}
+
+	_, body, err := get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+	assert.Equal(t, "alternate", body)
+}
+
+func TestHedger_Failure(t *testing.T) {
+	s := stubserver.New(
+		stubserver.Route{Path: "/fail", Latency: 50 * time.Millisecond, FailEvery: 1},
+		stubserver.Route{Path: "/failfast", FailEvery: 1},
+	)
+	defer s.Close()
+	c := &client.Hedger{Caller: &client.BaseClient{}, Options: client.HedgeOptions{Delay: 10 * time.Millisecond}}
+
+	// both requests fail: the last response is returned
+	status, _, err := get(c, http.MethodGet, s.URL+"/fail")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	s.AssertCalls(t, http.MethodGet, "/fail", 2)
+
+	// the request fails before the delay: no hedged request is sent
+	status, _, err = get(c, http.MethodGet, s.URL+"/failfast")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, status)
+	s.AssertCalls(t, http.MethodGet, "/failfast", 1)
+}
+
+func TestHedger_LatencyMetrics(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "hello", Latency: 300 * time.Millisecond})
+	defer s.Close()
+	metrics := client.Metrics{Latency: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Name:       "hedger_latency_api_latency",
+		Help:       "Latency of API calls",
+		Objectives: map[float64]float64{0.5: 0.05, 0.95: 0.01},
+	}, []string{"application", "endpoint", "method"})}
+	c := &client.Hedger{Caller: &client.BaseClient{}, Options: client.HedgeOptions{
+		Application:    "foo",
+		LatencyMetrics: metrics,
+		Percentile:     0.95,
+		Delay:          time.Second,
+	}}
+
+	// not enough measurements: the fallback delay applies and no hedged request is sent
+	_, _, err := get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+	s.AssertCalls(t, http.MethodGet, "/foo", 1)
+
+	// the p95 is 1 sec, so the request isn't hedged, even though the mean latency is about 100 msec
+	observer := metrics.Latency.WithLabelValues("foo", "/foo", http.MethodGet)
+	for i := 0; i < 100; i++ {
+		latency := 0.01
+		if i%10 == 0 {
+			latency = 1
+		}
+		observer.Observe(latency)
+	}
+	_, _, err = get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+	s.AssertCalls(t, http.MethodGet, "/foo", 2)
+
+	// the p95 is 10 msec: the request is hedged
+	for i := 0; i < 1000; i++ {
+		observer.Observe(0.01)
+	}
+	_, _, err = get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+	s.AssertCalls(t, http.MethodGet, "/foo", 4)
+}
+
+func TestHedger_NoObjectives(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "hello", Latency: 300 * time.Millisecond})
+	defer s.Close()
+	metrics := client.NewMetrics("hedger_objectives", "")
+	c := &client.Hedger{Caller: &client.BaseClient{}, Options: client.HedgeOptions{
+		Application:    "foo",
+		LatencyMetrics: metrics,
+		Delay:          time.Second,
+	}}
+
+	// the latency summary has no quantiles: the fallback delay applies, rather than the mean latency
+	for i := 0; i < 20; i++ {
+		metrics.Latency.WithLabelValues("foo", "/foo", http.MethodGet).Observe(0.01)
+	}
+	_, _, err := get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+	s.AssertCalls(t, http.MethodGet, "/foo", 1)
+}
+
+func TestHedger_Instrumented(t *testing.T) {
+	var cancelled atomic.Bool
+	s := stubserver.New(stubserver.Route{Path: "/slow", Handler: slowFirst(time.Second, &cancelled)})
+	defer s.Close()
+	metrics := client.NewMetrics("hedger_instrumented", "")
+	c := &client.Hedger{
+		Caller:  &client.InstrumentedClient{Options: client.Options{PrometheusMetrics: metrics}, Application: "foo"},
+		Options: client.HedgeOptions{Application: "foo", LatencyMetrics: metrics, Delay: 20 * time.Millisecond},
+	}
+
+	_, _, err := get(c, http.MethodGet, s.URL+"/slow")
+	require.NoError(t, err)
+	require.Eventually(t, cancelled.Load, time.Second, 10*time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	// the cancelled request is neither an error, nor measured
+	labels := prometheus.Labels{"application": "foo", "endpoint": "/slow", "method": http.MethodGet}
+	m, err := tools.Collect(metrics.Errors)
+	require.NoError(t, err)
+	value, err := m.Counter("hedger_instrumented_api_errors_total", labels)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, value)
+	m, err = tools.Collect(metrics.Latency)
+	require.NoError(t, err)
+	summary, err := m.Summary("hedger_instrumented_api_latency", labels)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), summary.GetSampleCount())
+}
+
+func TestHedger_LatencyMetrics_NoSeries(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "hello"})
+	defer s.Close()
+	metrics := client.Metrics{Latency: prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Name: "hedger_series_api_latency",
+		Help: "Latency of API calls",
+	}, []string{"application", "endpoint", "method"})}
+	c := &client.Hedger{Caller: &client.BaseClient{}, Options: client.HedgeOptions{Application: "foo", LatencyMetrics: metrics}}
+
+	// looking up the latency doesn't create a series
+	_, _, err := get(c, http.MethodGet, s.URL+"/foo")
+	require.NoError(t, err)
+	m, err := tools.Collect(metrics.Latency)
+	require.NoError(t, err)
+	assert.Empty(t, m)
+}
+
+// slowFirst returns a handler that delays its first response by delay, unless the request is cancelled
+func slowFirst(delay time.Duration, cancelled *atomic.Bool) http.Handler {
+	var calls atomic.Int32
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if calls.Add(1) == 1 {
+			select {
+			case <-req.Context().Done():
+				cancelled.Store(true)
+				return
+			case <-time.After(delay):
+			}
+		}
+		_, _ = w.Write([]byte("hello"))
+	})
+}
+
+func get(c client.Caller, method, url string) (status int, body string, err error) {
+	req, _ := http.NewRequest(method, url, nil)
+	var resp *http.Response
+	if resp, err = c.Do(req); err != nil {
+		return
+	}
+	defer func() { _ = resp.Body.Close() }()
+	var content []byte
+	content, err = io.ReadAll(resp.Body)
+	return resp.StatusCode, string(content), err
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"errors"
+	"github.com/clambin/go-metrics/internal/sanitize"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPError is returned when an API responds with a non-2xx status code. Use errors.Is to classify the error:
+//
+//	if errors.Is(err, client.ErrClientError) {
+//		// 4xx
+//	}
+type HTTPError struct {
+	// StatusCode of the response
+	StatusCode int
+	// Status of the response (e.g. "404 Not Found")
+	Status string
+	// Method of the request
+	Method string
+	// URL of the request. The values of sensitive query parameters (e.g. key or access_token) are redacted
+	URL string
+	// Header contains the headers of the response
+	Header http.Header
+	// Body of the response, truncated to MaxErrorBodySize bytes
+	Body []byte
+	// RetryAfter is the delay requested by the response's Retry-After header. Zero if the header is absent or invalid
+	RetryAfter time.Duration
+}
+
+// MaxErrorBodySize is the maximum size of the response body stored in an HTTPError
+const MaxErrorBodySize = 4096
+
+var (
+	// ErrClientError matches any HTTPError with a 4xx status code
+	ErrClientError = errors.New("client error")
+	// ErrServerError matches any HTTPError with a 5xx status code
+	ErrServerError = errors.New("server error")
+)
+
+// Error implements the error interface
+func (e *HTTPError) Error() string {
+	return e.Method + " " + e.URL + ": " + e.Status
+}
+
+// Is reports whether the HTTPError matches the target: ErrClientError for 4xx status codes, ErrServerError for 5xx
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case ErrClientError:
+		return e.StatusCode >= 400 && e.StatusCode < 500
+	case ErrServerError:
+		return e.StatusCode >= 500 && e.StatusCode < 600
+	}
+	return false
+}
+
+// newHTTPError creates an HTTPError for the response. It reads the response's body, but doesn't close it.
+func newHTTPError(resp *http.Response) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, MaxErrorBodySize))
+	e := &HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Header:     resp.Header,
+		Body:       body,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+	if resp.Request != nil {
+		e.Method = resp.Request.Method
+		e.URL = sanitize.URL(resp.Request.URL, sanitize.DefaultQueryParams)
+	}
+	return e
+}
+
+// parseRetryAfter parses a Retry-After header, which holds either a number of seconds or an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+	return 0
+}
+
+func isSuccess(resp *http.Response) bool {
+	return resp.StatusCode >= 200 && resp.StatusCode < 300
+}
+
+// StatusChecker is a Caller that converts any non-2xx response into an *HTTPError. It closes the body of such
+// responses, so callers only need to handle successful responses:
+//
+//	c := &client.StatusChecker{Caller: &client.BaseClient{}}
+//	resp, err := c.Do(req)
+//	if errors.Is(err, client.ErrServerError) {
+//		// retry later
+//	}
+type StatusChecker struct {
+	Caller
+}
+
+var _ Caller = &StatusChecker{}
+
+// Do sends the request and returns an *HTTPError if the response has a non-2xx status code
+func (s *StatusChecker) Do(req *http.Request) (resp *http.Response, err error) {
+	if resp, err = s.Caller.Do(req); err != nil || isSuccess(resp) {
+		return
+	}
+	err = newHTTPError(resp)
+	_ = resp.Body.Close()
+	return nil, err
+}
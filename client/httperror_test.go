@@ -0,0 +1,54 @@
+package client_test
+
+import (
+	"errors"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusChecker(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/ok").Return(http.StatusOK, "ok")
+	m.Expect(http.MethodGet, "/throttled").Return(http.StatusTooManyRequests, "slow down").ReturnHeader("Retry-After", "30")
+	m.Expect(http.MethodGet, "/down").Return(http.StatusServiceUnavailable, strings.Repeat("x", 2*client.MaxErrorBodySize)).
+		ReturnHeader("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	c := &client.StatusChecker{Caller: m}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/ok", nil)
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_ = resp.Body.Close()
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost/throttled", nil)
+	_, err = c.Do(req)
+	require.Error(t, err)
+	assert.Equal(t, "GET http://localhost/throttled: 429 Too Many Requests", err.Error())
+	assert.True(t, errors.Is(err, client.ErrClientError))
+	assert.False(t, errors.Is(err, client.ErrServerError))
+	var httpErr *client.HTTPError
+	require.True(t, errors.As(err, &httpErr))
+	assert.Equal(t, http.StatusTooManyRequests, httpErr.StatusCode)
+	assert.Equal(t, http.MethodGet, httpErr.Method)
+	assert.Equal(t, "slow down", string(httpErr.Body))
+	assert.Equal(t, 30*time.Second, httpErr.RetryAfter)
+	assert.Equal(t, "30", httpErr.Header.Get("Retry-After"))
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost/down", nil)
+	_, err = c.Do(req)
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, client.ErrServerError))
+	require.True(t, errors.As(err, &httpErr))
+	assert.Len(t, httpErr.Body, client.MaxErrorBodySize)
+	assert.Greater(t, httpErr.RetryAfter, 59*time.Minute)
+
+	req, _ = http.NewRequest(http.MethodGet, "http://localhost/invalid", nil)
+	_, err = c.Do(req)
+	assert.True(t, errors.Is(err, mock.ErrUnexpectedRequest))
+}
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"net/http"
 )
 
@@ -22,14 +24,23 @@ type Options struct {
 // Do implements the Caller's Do() method. It sends the request and records performance metrics of the call.
 // Currently, it records the request's duration (i.e. latency) and error rate.
 func (c *InstrumentedClient) Do(req *http.Request) (resp *http.Response, err error) {
+	return c.Options.call(&c.BaseClient, c.Application, req)
+}
+
+// call sends the request to the next Caller and records performance metrics of the call. Calls cancelled by the caller
+// (e.g. the losing request of a Hedger) aren't recorded: they are neither an error, nor a measure of the API's latency.
+func (o Options) call(next Caller, application string, req *http.Request) (resp *http.Response, err error) {
 	endpoint := req.URL.Path
-	timer := c.Options.PrometheusMetrics.MakeLatencyTimer(c.Application, endpoint, req.Method)
+	timer := o.PrometheusMetrics.MakeLatencyTimer(application, endpoint, req.Method)
 
-	resp, err = c.BaseClient.Do(req)
+	resp, err = next.Do(req)
 
+	if errors.Is(err, context.Canceled) || errors.Is(req.Context().Err(), context.Canceled) {
+		return
+	}
 	if timer != nil {
 		timer.ObserveDuration()
 	}
-	c.Options.PrometheusMetrics.ReportErrors(err, c.Application, endpoint, req.Method)
+	o.PrometheusMetrics.ReportErrors(err, application, endpoint, req.Method)
 	return
 }
@@ -4,18 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/stubserver"
 	"github.com/clambin/go-metrics/tools"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"net/http"
-	"net/http/httptest"
 	"testing"
 )
 
 func TestClient_Do(t *testing.T) {
 	metrics := client.NewMetrics("foo", "bar")
-	s := httptest.NewServer(http.HandlerFunc(handler))
+	s := stubserver.New(stubserver.Route{Path: "/foo", JSON: testStruct{Name: "bar", Age: 42}})
 	c := &client.InstrumentedClient{
 		Options:     client.Options{PrometheusMetrics: metrics},
 		Application: "foo",
@@ -33,38 +33,25 @@ func TestClient_Do(t *testing.T) {
 	response, err = doCall(c, s.URL+"/foo")
 	require.Error(t, err)
 
-	ch := make(chan prometheus.Metric)
-	go metrics.Latency.Collect(ch)
-
-	expectedLatencyCounters := map[string]uint64{
-		"/foo": 2,
-		"/bar": 1,
-	}
-
-	for range expectedLatencyCounters {
-		desc := <-ch
-		endpoint := *tools.MetricValue(desc).GetLabel()[1].Value
-		expected, ok := expectedLatencyCounters[endpoint]
-		require.True(t, ok)
-		assert.Equal(t, expected, tools.MetricValue(desc).GetSummary().GetSampleCount(), endpoint)
-	}
+	latency, err := tools.Collect(metrics.Latency)
+	require.NoError(t, err)
+	assert.Len(t, latency, 2)
 
-	ch = make(chan prometheus.Metric)
-	go metrics.Errors.Collect(ch)
-	expectedErrorCounters := map[string]float64{
-		"/foo": 1,
-		"/bar": 0,
+	for endpoint, expected := range map[string]uint64{"/foo": 2, "/bar": 1} {
+		summary, err := latency.Summary("foo_bar_api_latency", prometheus.Labels{"application": "foo", "endpoint": endpoint, "method": http.MethodGet})
+		require.NoError(t, err)
+		assert.Equal(t, expected, summary.GetSampleCount(), endpoint)
 	}
 
-	for range expectedErrorCounters {
-		desc := <-ch
-		endpoint := *tools.MetricValue(desc).GetLabel()[1].Value
-		expected, ok := expectedErrorCounters[endpoint]
-		require.True(t, ok)
-		assert.Equal(t, expected, tools.MetricValue(desc).GetCounter().GetValue(), endpoint)
+	errs, err := tools.Collect(metrics.Errors)
+	require.NoError(t, err)
+	assert.Len(t, errs, 2)
 
+	for endpoint, expected := range map[string]float64{"/foo": 1, "/bar": 0} {
+		value, err := errs.Counter("foo_bar_api_errors_total", prometheus.Labels{"application": "foo", "endpoint": endpoint, "method": http.MethodGet})
+		require.NoError(t, err)
+		assert.Equal(t, expected, value, endpoint)
 	}
-
 }
 
 type testStruct struct {
@@ -86,15 +73,3 @@ func doCall(c client.Caller, url string) (response testStruct, err error) {
 	err = json.NewDecoder(resp.Body).Decode(&response)
 	return
 }
-
-func handler(w http.ResponseWriter, req *http.Request) {
-	if req.URL.Path != "/foo" {
-		http.Error(w, "invalid endpoint", http.StatusNotFound)
-		return
-	}
-
-	_ = json.NewEncoder(w).Encode(testStruct{
-		Name: "bar",
-		Age:  42,
-	})
-}
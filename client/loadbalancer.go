@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoadBalancer spreads requests across a set of targets (e.g. the replicas of a service). It rewrites each request's
+// scheme and host (and prefixes its path with the target's path, if any) to those of the selected target:
+//
+//	c := &client.LoadBalancer{Caller: &client.BaseClient{}, Options: client.LoadBalancerOptions{
+//		Targets:  []client.Target{{URL: "http://10.0.0.1:8080"}, {URL: "http://10.0.0.2:8080"}},
+//		Strategy: client.LeastInFlight,
+//	}}
+//
+// LoadBalancer tracks the health of each target passively: once a target fails MaxFailures consecutive calls
+// (i.e. the call returns an error, or a 5xx status code), it is ejected for EjectionTime. If all targets are ejected,
+// requests are spread across all targets. Calls cancelled by the caller (e.g. the losing request of a Hedger) say
+// nothing about the target's health: they are neither counted as a failure nor reported in the metrics.
+//
+// If Targets is empty, or holds an invalid URL, Do returns an error.
+type LoadBalancer struct {
+	Caller
+	Options LoadBalancerOptions
+	targets []*target
+	err     error
+	next    int
+	once    sync.Once
+	lock    sync.Mutex
+}
+
+var _ Caller = &LoadBalancer{}
+
+// LoadBalancerOptions contains options to alter LoadBalancer behaviour
+type LoadBalancerOptions struct {
+	// Targets to spread the requests across
+	Targets []Target
+	// Strategy determines how the target of each request is selected
+	Strategy Strategy
+	// MaxFailures is the number of consecutive failed calls after which a target is ejected. If zero, 5 is used
+	MaxFailures int
+	// EjectionTime is how long an ejected target receives no requests. If zero, 30 seconds is used
+	EjectionTime time.Duration
+	// Application is the application label of the metrics
+	Application string
+	// Metrics records the calls to each target. If the metrics are nil, no metrics are recorded
+	Metrics LoadBalancerMetrics
+}
+
+// Target is a single target of a LoadBalancer
+type Target struct {
+	// URL of the target, e.g. "http://10.0.0.1:8080". If it holds a path, that path prefixes the request's path
+	URL string
+	// Weight of the target in the Weighted strategy. If zero, 1 is used
+	Weight int
+}
+
+// Strategy determines how a LoadBalancer selects the target for a request
+type Strategy int
+
+const (
+	// RoundRobin selects each target in turn
+	RoundRobin Strategy = iota
+	// LeastInFlight selects the target with the fewest calls in progress
+	LeastInFlight
+	// Weighted selects targets in proportion to their Weight
+	Weighted
+)
+
+type target struct {
+	name         string
+	url          *url.URL
+	weight       int
+	current      int
+	inFlight     int
+	failures     int
+	ejectedUntil time.Time
+}
+
+// Do sends the request to the selected target
+func (l *LoadBalancer) Do(req *http.Request) (resp *http.Response, err error) {
+	if l.once.Do(l.init); l.err != nil {
+		return nil, l.err
+	}
+	t := l.pick()
+	defer l.release(t)
+
+	r := req.Clone(req.Context())
+	r.URL.Scheme = t.url.Scheme
+	r.URL.Host = t.url.Host
+	if t.url.Path != "" {
+		r.URL.Path = strings.TrimSuffix(t.url.Path, "/") + r.URL.Path
+		r.URL.RawPath = ""
+	}
+	r.Host = ""
+
+	start := time.Now()
+	resp, err = l.Caller.Do(r)
+	if errors.Is(err, context.Canceled) || errors.Is(req.Context().Err(), context.Canceled) {
+		return
+	}
+	failed := err != nil || resp.StatusCode >= 500
+	l.Options.Metrics.report(l.Options.Application, t.name, time.Since(start), failed)
+	l.report(t, failed)
+	return
+}
+
+func (l *LoadBalancer) init() {
+	if len(l.Options.Targets) == 0 {
+		l.err = errors.New("loadBalancer: no targets")
+		return
+	}
+	targets := make([]*target, 0, len(l.Options.Targets))
+	for _, entry := range l.Options.Targets {
+		u, err := url.Parse(entry.URL)
+		if err != nil || u.Host == "" {
+			l.err = fmt.Errorf("loadBalancer: invalid target '%s'", entry.URL)
+			return
+		}
+		weight := entry.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		targets = append(targets, &target{name: entry.URL, url: u, weight: weight})
+	}
+	l.targets = targets
+	for _, t := range l.targets {
+		l.Options.Metrics.reportHealthy(l.Options.Application, t.name, true)
+	}
+}
+
+// pick selects the target for the next request
+func (l *LoadBalancer) pick() *target {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := time.Now()
+	available := make([]*target, 0, len(l.targets))
+	for index := range l.targets {
+		// start at the next target, so RoundRobin and ties in LeastInFlight rotate across targets
+		t := l.targets[(l.next+index)%len(l.targets)]
+		if t.ejectedUntil.Before(now) {
+			available = append(available, t)
+		}
+	}
+	if len(available) == 0 {
+		for index := range l.targets {
+			available = append(available, l.targets[(l.next+index)%len(l.targets)])
+		}
+	}
+	l.next = (l.next + 1) % len(l.targets)
+
+	selected := available[0]
+	switch l.Options.Strategy {
+	case LeastInFlight:
+		for _, t := range available {
+			if t.inFlight < selected.inFlight {
+				selected = t
+			}
+		}
+	case Weighted:
+		// smooth weighted round-robin: each target accumulates its weight; the target with the highest total is
+		// selected, and its total is reduced by the sum of all weights
+		var total int
+		for _, t := range available {
+			t.current += t.weight
+			total += t.weight
+			if t.current > selected.current {
+				selected = t
+			}
+		}
+		selected.current -= total
+	}
+	selected.inFlight++
+	return selected
+}
+
+func (l *LoadBalancer) release(t *target) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	t.inFlight--
+}
+
+// report records the outcome of a call to the target, ejecting the target after MaxFailures consecutive failures
+func (l *LoadBalancer) report(t *target, failed bool) {
+	maxFailures := l.Options.MaxFailures
+	if maxFailures == 0 {
+		maxFailures = 5
+	}
+	ejectionTime := l.Options.EjectionTime
+	if ejectionTime == 0 {
+		ejectionTime = 30 * time.Second
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if !failed {
+		t.failures = 0
+		if !t.ejectedUntil.IsZero() && t.ejectedUntil.Before(time.Now()) {
+			t.ejectedUntil = time.Time{}
+			l.Options.Metrics.reportHealthy(l.Options.Application, t.name, true)
+		}
+		return
+	}
+	if t.failures++; t.failures >= maxFailures {
+		t.failures = 0
+		t.ejectedUntil = time.Now().Add(ejectionTime)
+		l.Options.Metrics.reportEjection(l.Options.Application, t.name)
+	}
+}
+
+// WithLoadBalancing spreads requests across a set of targets, like LoadBalancer
+func WithLoadBalancing(options LoadBalancerOptions) Middleware {
+	return Middleware{
+		Name: "loadbalancer",
+		Wrap: func(next Caller) Caller {
+			return &LoadBalancer{Caller: next, Options: options}
+		},
+	}
+}
@@ -0,0 +1,198 @@
+package client_test
+
+import (
+	"context"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestLoadBalancer_RoundRobin(t *testing.T) {
+	servers := startServers(3, stubserver.Route{Path: "/foo"})
+	defer stopServers(servers)
+
+	c := &client.LoadBalancer{Caller: &client.BaseClient{}, Options: client.LoadBalancerOptions{Targets: targets(servers)}}
+	for i := 0; i < 6; i++ {
+		status, _, err := get(c, http.MethodGet, "http://service/foo")
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, status)
+	}
+	for _, s := range servers {
+		s.AssertCalls(t, http.MethodGet, "/foo", 2)
+	}
+}
+
+func TestLoadBalancer_Weighted(t *testing.T) {
+	servers := startServers(2, stubserver.Route{Path: "/foo"})
+	defer stopServers(servers)
+
+	c := &client.LoadBalancer{Caller: &client.BaseClient{}, Options: client.LoadBalancerOptions{
+		Targets:  []client.Target{{URL: servers[0].URL, Weight: 3}, {URL: servers[1].URL}},
+		Strategy: client.Weighted,
+	}}
+	for i := 0; i < 8; i++ {
+		_, _, err := get(c, http.MethodGet, "http://service/foo")
+		require.NoError(t, err)
+	}
+	servers[0].AssertCalls(t, http.MethodGet, "/foo", 6)
+	servers[1].AssertCalls(t, http.MethodGet, "/foo", 2)
+}
+
+func TestLoadBalancer_LeastInFlight(t *testing.T) {
+	blocked := make(chan struct{})
+	busy := stubserver.New(stubserver.Route{Path: "/foo", Handler: http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {
+		<-blocked
+	})})
+	defer busy.Close()
+	idle := stubserver.New(stubserver.Route{Path: "/foo"})
+	defer idle.Close()
+
+	c := &client.LoadBalancer{Caller: &client.BaseClient{}, Options: client.LoadBalancerOptions{
+		Targets:  []client.Target{{URL: busy.URL}, {URL: idle.URL}},
+		Strategy: client.LeastInFlight,
+	}}
+
+	done := make(chan struct{})
+	go func() {
+		_, _, _ = get(c, http.MethodGet, "http://service/foo")
+		close(done)
+	}()
+	require.Eventually(t, func() bool { return busy.Calls(http.MethodGet, "/foo") == 1 }, time.Second, 10*time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		_, _, err := get(c, http.MethodGet, "http://service/foo")
+		require.NoError(t, err)
+	}
+	close(blocked)
+	<-done
+
+	busy.AssertCalls(t, http.MethodGet, "/foo", 1)
+	idle.AssertCalls(t, http.MethodGet, "/foo", 4)
+}
+
+func TestLoadBalancer_Ejection(t *testing.T) {
+	failing := stubserver.New(stubserver.Route{Path: "/foo", FailEvery: 1})
+	defer failing.Close()
+	healthy := stubserver.New(stubserver.Route{Path: "/foo"})
+	defer healthy.Close()
+	metrics := client.NewLoadBalancerMetrics("loadbalancer", "")
+
+	c := &client.LoadBalancer{Caller: &client.BaseClient{}, Options: client.LoadBalancerOptions{
+		Targets:      []client.Target{{URL: failing.URL}, {URL: healthy.URL}},
+		MaxFailures:  2,
+		EjectionTime: 200 * time.Millisecond,
+		Application:  "foo",
+		Metrics:      metrics,
+	}}
+	for i := 0; i < 10; i++ {
+		_, _, err := get(c, http.MethodGet, "http://service/foo")
+		require.NoError(t, err)
+	}
+	failing.AssertCalls(t, http.MethodGet, "/foo", 2)
+	healthy.AssertCalls(t, http.MethodGet, "/foo", 8)
+
+	labels := prometheus.Labels{"application": "foo", "target": failing.URL}
+	m, err := tools.Collect(metrics.Ejections)
+	require.NoError(t, err)
+	value, err := m.Counter("loadbalancer_api_target_ejections_total", labels)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+	m, err = tools.Collect(metrics.Healthy)
+	require.NoError(t, err)
+	value, err = m.Gauge("loadbalancer_api_target_healthy", labels)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, value)
+	m, err = tools.Collect(metrics.Errors)
+	require.NoError(t, err)
+	value, err = m.Counter("loadbalancer_api_target_errors_total", labels)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, value)
+
+	// once the ejection time has passed, the target receives requests again
+	time.Sleep(200 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		_, _, err = get(c, http.MethodGet, "http://service/foo")
+		require.NoError(t, err)
+	}
+	failing.AssertCalls(t, http.MethodGet, "/foo", 3)
+}
+
+func TestLoadBalancer_Path(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/api/v1/foo", Body: "hello"})
+	defer s.Close()
+
+	c := client.Build(nil, client.WithLoadBalancing(client.LoadBalancerOptions{Targets: []client.Target{{URL: s.URL + "/api/v1/"}}}))
+	_, body, err := get(c, http.MethodGet, "http://service/foo")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", body)
+}
+
+func TestLoadBalancer_Invalid(t *testing.T) {
+	c := &client.LoadBalancer{Caller: &client.BaseClient{}}
+	for i := 0; i < 2; i++ {
+		_, _, err := get(c, http.MethodGet, "http://service/foo")
+		assert.EqualError(t, err, "loadBalancer: no targets")
+	}
+
+	c = &client.LoadBalancer{Caller: &client.BaseClient{}, Options: client.LoadBalancerOptions{Targets: []client.Target{{URL: "foo"}}}}
+	for i := 0; i < 2; i++ {
+		_, _, err := get(c, http.MethodGet, "http://service/foo")
+		assert.EqualError(t, err, "loadBalancer: invalid target 'foo'")
+	}
+}
+
+func startServers(count int, routes ...stubserver.Route) (servers []*stubserver.Server) {
+	for i := 0; i < count; i++ {
+		servers = append(servers, stubserver.New(routes...))
+	}
+	return
+}
+
+func stopServers(servers []*stubserver.Server) {
+	for _, s := range servers {
+		s.Close()
+	}
+}
+
+func targets(servers []*stubserver.Server) (targets []client.Target) {
+	for _, s := range servers {
+		targets = append(targets, client.Target{URL: s.URL})
+	}
+	return
+}
+
+func TestLoadBalancer_Cancelled(t *testing.T) {
+	var hosts []string
+	c := &client.LoadBalancer{
+		Caller: callerFunc(func(req *http.Request) (*http.Response, error) {
+			if err := req.Context().Err(); err != nil {
+				return nil, err
+			}
+			hosts = append(hosts, req.URL.Host)
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		}),
+		Options: client.LoadBalancerOptions{
+			Targets:     []client.Target{{URL: "http://10.0.0.1"}, {URL: "http://10.0.0.2"}},
+			MaxFailures: 1,
+		},
+	}
+
+	// a call cancelled by the caller doesn't eject the target
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://service/foo", nil)
+	_, err := c.Do(req)
+	require.ErrorIs(t, err, context.Canceled)
+
+	for i := 0; i < 4; i++ {
+		_, _, err = get(c, http.MethodGet, "http://service/foo")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, []string{"10.0.0.2", "10.0.0.1", "10.0.0.2", "10.0.0.1"}, hosts)
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/clambin/go-metrics/internal/sanitize"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Logger logs each call with log/slog:
+//
+//	c := &client.Logger{Caller: cacher, Logger: slog.Default()}
+//
+// Each record holds the request's method, URL, normalised endpoint, the response's status code and the call's duration.
+// Sensitive headers and query parameters are redacted, including in the error of a failed call. If the chain below
+// Logger contains a Cacher, the record shows whether the response was cached. If it contains a Retrier, the record
+// shows the number of attempts. If Logger is placed below a Retrier, each attempt is logged separately, with its
+// attempt number.
+type Logger struct {
+	Caller
+	// Logger receives the records. If nil, slog.Default() is used
+	Logger *slog.Logger
+	// Options contains options to alter Logger behaviour
+	Options LogOptions
+	calls   atomic.Uint64
+}
+
+var _ Caller = &Logger{}
+
+// LogOptions contains options to alter Logger behaviour
+type LogOptions struct {
+	// Level determines the level of the record for a call's outcome. If nil, DefaultLogLevel is used
+	Level func(resp *http.Response, err error) slog.Level
+	// Sample logs only one in every Sample successful calls. Failed calls are always logged. If zero, all calls are logged
+	Sample int
+	// LogHeaders adds the request's headers to each record
+	LogHeaders bool
+	// RedactHeaders contains the headers whose value is not logged. If nil, a default set (e.g. Authorization) is used
+	RedactHeaders []string
+	// RedactQueryParams contains the query parameters whose value is not logged. If nil, a default set
+	// (e.g. access_token) is used
+	RedactQueryParams []string
+}
+
+// DefaultLogLevel logs failed calls and 5xx responses at Error level, 4xx responses at Warn level and all others
+// at Info level
+func DefaultLogLevel(resp *http.Response, err error) slog.Level {
+	switch {
+	case err != nil || resp.StatusCode >= 500:
+		return slog.LevelError
+	case resp.StatusCode >= 400:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// Do sends the request and logs the outcome
+func (l *Logger) Do(req *http.Request) (resp *http.Response, err error) {
+	info := getCallInfo(req.Context())
+	if info == nil {
+		info = &callInfo{}
+		req = req.WithContext(context.WithValue(req.Context(), callInfoKey{}, info))
+	}
+	start := time.Now()
+	resp, err = l.Caller.Do(req)
+	duration := time.Since(start)
+
+	failed := err != nil || resp.StatusCode >= 400
+	if !failed && l.Options.Sample > 1 && (l.calls.Add(1)-1)%uint64(l.Options.Sample) != 0 {
+		return
+	}
+
+	levelFunc := DefaultLogLevel
+	if l.Options.Level != nil {
+		levelFunc = l.Options.Level
+	}
+	level := levelFunc(resp, err)
+	logger := l.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if !logger.Enabled(req.Context(), level) {
+		return
+	}
+	logger.LogAttrs(req.Context(), level, "api call", l.Options.attrs(req, resp, err, duration, info)...)
+	return
+}
+
+func (o LogOptions) attrs(req *http.Request, resp *http.Response, err error, duration time.Duration, info *callInfo) []slog.Attr {
+	redactQueryParams := o.RedactQueryParams
+	if redactQueryParams == nil {
+		redactQueryParams = sanitize.DefaultQueryParams
+	}
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", sanitize.URL(req.URL, redactQueryParams)),
+		slog.String("endpoint", sanitize.Path(req.URL.Path)),
+	}
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	attrs = append(attrs, slog.Duration("duration", duration))
+	if cache := info.cache.Load(); cache != cacheUnknown {
+		attrs = append(attrs, slog.Bool("cache_hit", cache == cacheHit))
+	}
+	if attempt, ok := req.Context().Value(attemptKey{}).(int); ok {
+		attrs = append(attrs, slog.Int("attempt", attempt))
+	} else if attempts := info.attempts.Load(); attempts > 0 {
+		attrs = append(attrs, slog.Int("attempts", int(attempts)))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", redactError(err, req, redactQueryParams)))
+	}
+	if o.LogHeaders {
+		redactHeaders := o.RedactHeaders
+		if redactHeaders == nil {
+			redactHeaders = sanitize.DefaultHeaders
+		}
+		attrs = append(attrs, slog.Any("headers", sanitize.Header(req.Header, redactHeaders)))
+	}
+	return attrs
+}
+
+// redactError returns the error's text, with the query parameters redacted in any URL it contains. Both *url.Error
+// and *HTTPError include the request's URL.
+func redactError(err error, req *http.Request, params []string) string {
+	urls := []string{req.URL.String()}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		urls = append(urls, urlErr.URL)
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		urls = append(urls, httpErr.URL)
+	}
+	text := err.Error()
+	for _, rawURL := range urls {
+		if parsed, parseErr := url.Parse(rawURL); parseErr == nil && rawURL != "" {
+			text = strings.ReplaceAll(text, rawURL, sanitize.URL(parsed, params))
+		}
+	}
+	return text
+}
+
+// callInfo records what happened to a request further down the Caller chain, so Logger can log it
+type callInfo struct {
+	cache    atomic.Int32
+	attempts atomic.Int32
+}
+
+type callInfoKey struct{}
+
+// attemptKey holds the attempt number of a request sent by a Retrier
+type attemptKey struct{}
+
+const (
+	cacheUnknown int32 = iota
+	cacheMiss
+	cacheHit
+)
+
+func getCallInfo(ctx context.Context) *callInfo {
+	info, _ := ctx.Value(callInfoKey{}).(*callInfo)
+	return info
+}
+
+// reportCacheLookup records whether the request's response was found in the cache
+func reportCacheLookup(req *http.Request, found bool) {
+	if info := getCallInfo(req.Context()); info != nil {
+		outcome := cacheMiss
+		if found {
+			outcome = cacheHit
+		}
+		info.cache.Store(outcome)
+	}
+}
+
+// reportAttempt records the attempt number of a request sent by a Retrier
+func reportAttempt(req *http.Request, attempt int) *http.Request {
+	if info := getCallInfo(req.Context()); info != nil {
+		info.attempts.Store(int32(attempt))
+	}
+	return req.WithContext(context.WithValue(req.Context(), attemptKey{}, attempt))
+}
+
+// WithLogging logs each call, like Logger
+func WithLogging(logger *slog.Logger, options LogOptions) Middleware {
+	return Middleware{
+		Name: "logging",
+		Wrap: func(next Caller) Caller {
+			return &Logger{Caller: next, Logger: logger, Options: options}
+		},
+	}
+}
@@ -0,0 +1,162 @@
+package client_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/users/123").Return(http.StatusOK, "")
+	m.Expect(http.MethodGet, "/missing").Return(http.StatusNotFound, "")
+	m.Expect(http.MethodGet, "/fail").ReturnError(errors.New("connection refused"))
+	c := &client.Logger{
+		Caller:  m,
+		Logger:  slog.New(slog.NewJSONHandler(&buf, nil)),
+		Options: client.LogOptions{LogHeaders: true},
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "http://localhost/users/123?api_key=secret&page=1", nil)
+	req.Header.Set("Authorization", "Bearer 123")
+	_, err := c.Do(req)
+	require.NoError(t, err)
+	_, err = call(c, http.MethodGet, "/missing", "")
+	require.NoError(t, err)
+	_, err = call(c, http.MethodGet, "/fail", "")
+	require.Error(t, err)
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 3)
+	assert.Equal(t, "INFO", records[0]["level"])
+	assert.Equal(t, "GET", records[0]["method"])
+	assert.Equal(t, "http://localhost/users/123?api_key=REDACTED&page=1", records[0]["url"])
+	assert.Equal(t, "/users/:id", records[0]["endpoint"])
+	assert.Equal(t, 200.0, records[0]["status"])
+	assert.Equal(t, map[string]interface{}{"Authorization": []interface{}{"REDACTED"}}, records[0]["headers"])
+	assert.NotContains(t, records[0], "cache_hit")
+	assert.Equal(t, "WARN", records[1]["level"])
+	assert.Equal(t, 404.0, records[1]["status"])
+	assert.Equal(t, "ERROR", records[2]["level"])
+	assert.Equal(t, "connection refused", records[2]["error"])
+}
+
+func TestLogger_Chain(t *testing.T) {
+	var buf bytes.Buffer
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/foo").Return(http.StatusServiceUnavailable, "").Once()
+	m.Expect(http.MethodGet, "/foo").Return(http.StatusOK, "")
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	c := client.Build(m,
+		client.WithLogging(logger, client.LogOptions{}),
+		client.WithCache([]client.CacheTableEntry{{Endpoint: "/foo"}}, time.Minute, 0),
+		client.WithRetry(client.RetryOptions{Backoff: time.Millisecond}),
+		client.WithLogging(logger, client.LogOptions{}),
+	)
+	for i := 0; i < 2; i++ {
+		_, err := call(c, http.MethodGet, "/foo", "")
+		require.NoError(t, err)
+	}
+
+	records := logRecords(t, &buf)
+	require.Len(t, records, 4)
+	// inner logger: one record per attempt
+	assert.Equal(t, 1.0, records[0]["attempt"])
+	assert.Equal(t, 503.0, records[0]["status"])
+	assert.Equal(t, 2.0, records[1]["attempt"])
+	assert.Equal(t, 200.0, records[1]["status"])
+	// outer logger: one record per call
+	assert.Equal(t, false, records[2]["cache_hit"])
+	assert.Equal(t, 2.0, records[2]["attempts"])
+	assert.Equal(t, true, records[3]["cache_hit"])
+	assert.NotContains(t, records[3], "attempts")
+}
+
+func TestLogger_RedactError(t *testing.T) {
+	var buf bytes.Buffer
+	s := httptest.NewServer(http.NotFoundHandler())
+	c := client.Build(&client.BaseClient{},
+		client.WithLogging(slog.New(slog.NewJSONHandler(&buf, nil)), client.LogOptions{RedactQueryParams: []string{"key", "session"}}),
+		client.WithStatusCheck(),
+	)
+
+	// the API returns an error: the error contains the request's URL
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo?key=secret&session=secret", nil)
+	_, err := c.Do(req)
+	require.Error(t, err)
+
+	// the call fails: the *url.Error contains the request's URL
+	s.Close()
+	req, _ = http.NewRequest(http.MethodGet, s.URL+"/foo?key=secret", nil)
+	_, err = c.Do(req)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "secret")
+
+	assert.NotContains(t, buf.String(), "secret")
+	records := logRecords(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "GET "+s.URL+"/foo?key=REDACTED&session=REDACTED: 404 Not Found", records[0]["error"])
+	assert.Contains(t, records[1]["error"], s.URL+"/foo?key=REDACTED")
+}
+
+func TestLogger_Sample(t *testing.T) {
+	var buf bytes.Buffer
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/ok").Return(http.StatusOK, "")
+	m.Expect(http.MethodGet, "/fail").Return(http.StatusInternalServerError, "")
+	c := &client.Logger{
+		Caller: m,
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Options: client.LogOptions{
+			Sample: 3,
+			Level: func(_ *http.Response, err error) slog.Level {
+				return slog.LevelWarn
+			},
+		},
+	}
+
+	for i := 0; i < 6; i++ {
+		_, err := call(c, http.MethodGet, "/ok", "")
+		require.NoError(t, err)
+		_, err = call(c, http.MethodGet, "/fail", "")
+		require.NoError(t, err)
+	}
+
+	var ok, failed int
+	for _, record := range logRecords(t, &buf) {
+		assert.Equal(t, "WARN", record["level"])
+		switch record["status"] {
+		case 200.0:
+			ok++
+		case 500.0:
+			failed++
+		}
+	}
+	assert.Equal(t, 2, ok)
+	assert.Equal(t, 6, failed)
+}
+
+func logRecords(t *testing.T, buf *bytes.Buffer) (records []map[string]interface{}) {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return
+}
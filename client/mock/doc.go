@@ -0,0 +1,17 @@
+/*
+Package mock provides a programmable client.Caller, to unit test API clients without network access.
+
+Register the requests the Caller should expect, and the response for each request:
+
+	c := &mock.Caller{}
+	c.Expect(http.MethodGet, "/foo").ReturnJSON(http.StatusOK, response).Once()
+	c.Expect(http.MethodPost, "/bar").WithBody(`{"name":"foo"}`).Return(http.StatusCreated, "")
+	c.Expect(http.MethodGet, "/down").ReturnError(errors.New("connection refused"))
+
+	// pass c to the code under test
+
+	c.AssertExpectations(t)
+
+Requests returns all requests received by the Caller, so tests can inspect them.
+*/
+package mock
@@ -0,0 +1,194 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/tools"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Caller is a programmable client.Caller. Register the requests it should expect with Expect. Requests that don't
+// match any expectation fail with ErrUnexpectedRequest.
+type Caller struct {
+	expectations []*Expectation
+	requests     []Request
+	lock         sync.Mutex
+}
+
+var _ client.Caller = &Caller{}
+
+// ErrUnexpectedRequest is returned by Caller's Do method when a request doesn't match any expectation
+var ErrUnexpectedRequest = errors.New("unexpected request")
+
+// Request is a request received by Caller
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Expectation is a request expected by Caller, and the response to return for it. By default, an expectation
+// matches any number of calls and returns an empty response with status code 200.
+type Expectation struct {
+	method      string
+	path        string
+	bodyMatcher func(body []byte) bool
+	statusCode  int
+	header      http.Header
+	body        []byte
+	err         error
+	times       int
+	calls       int
+}
+
+// Expect registers a request the Caller should expect. If method is empty, the expectation matches any method.
+// Path must match the request's URL Path exactly.
+func (c *Caller) Expect(method, path string) *Expectation {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e := &Expectation{method: method, path: path, statusCode: http.StatusOK, header: make(http.Header)}
+	c.expectations = append(c.expectations, e)
+	return e
+}
+
+// WithBody only matches requests with the specified body
+func (e *Expectation) WithBody(body string) *Expectation {
+	return e.WithBodyMatcher(func(b []byte) bool { return string(b) == body })
+}
+
+// WithBodyMatcher only matches requests whose body is accepted by the matcher
+func (e *Expectation) WithBodyMatcher(matcher func(body []byte) bool) *Expectation {
+	e.bodyMatcher = matcher
+	return e
+}
+
+// Return sets the status code and body of the response
+func (e *Expectation) Return(statusCode int, body string) *Expectation {
+	e.statusCode = statusCode
+	e.body = []byte(body)
+	return e
+}
+
+// ReturnJSON sets the status code of the response and encodes the value as the response's body, in JSON format.
+// Panics if the value can't be encoded.
+func (e *Expectation) ReturnJSON(statusCode int, value interface{}) *Expectation {
+	body, err := json.Marshal(value)
+	if err != nil {
+		panic(fmt.Errorf("mock: invalid JSON response: %w", err))
+	}
+	e.header.Set("Content-Type", "application/json")
+	return e.Return(statusCode, string(body))
+}
+
+// ReturnHeader adds a header to the response
+func (e *Expectation) ReturnHeader(key, value string) *Expectation {
+	e.header.Add(key, value)
+	return e
+}
+
+// ReturnError makes the Caller return the error instead of a response
+func (e *Expectation) ReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// Times sets the number of calls the expectation should match. Once reached, the expectation no longer matches
+func (e *Expectation) Times(times int) *Expectation {
+	e.times = times
+	return e
+}
+
+// Once is equivalent to Times(1)
+func (e *Expectation) Once() *Expectation {
+	return e.Times(1)
+}
+
+func (e *Expectation) matches(req *http.Request, body []byte) bool {
+	return (e.method == "" || e.method == req.Method) &&
+		e.path == req.URL.Path &&
+		(e.bodyMatcher == nil || e.bodyMatcher(body)) &&
+		(e.times == 0 || e.calls < e.times)
+}
+
+func (e *Expectation) String() string {
+	method := e.method
+	if method == "" {
+		method = "*"
+	}
+	return method + " " + e.path
+}
+
+// Do returns the response of the first expectation that matches the request
+func (c *Caller) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		_ = req.Body.Close()
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.requests = append(c.requests, Request{Method: req.Method, URL: req.URL.String(), Header: req.Header.Clone(), Body: body})
+
+	for _, e := range c.expectations {
+		if e.matches(req, body) {
+			e.calls++
+			if e.err != nil {
+				return nil, e.err
+			}
+			return &http.Response{
+				Status:        strconv.Itoa(e.statusCode) + " " + http.StatusText(e.statusCode),
+				StatusCode:    e.statusCode,
+				Proto:         "HTTP/1.1",
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        e.header.Clone(),
+				Body:          io.NopCloser(bytes.NewReader(e.body)),
+				ContentLength: int64(len(e.body)),
+				Request:       req,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.String(), ErrUnexpectedRequest)
+}
+
+// Requests returns all requests received by the Caller, in the order in which they were received
+func (c *Caller) Requests() []Request {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]Request{}, c.requests...)
+}
+
+// AssertExpectations reports a test failure for each expectation that wasn't met: expectations set with Times
+// must be called exactly that many times. Other expectations must be called at least once.
+func (c *Caller) AssertExpectations(t tools.TestingT) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	ok := true
+	for _, e := range c.expectations {
+		switch {
+		case e.times == 0 && e.calls == 0:
+			t.Errorf("%s: expected at least one call", e.String())
+			ok = false
+		case e.times > 0 && e.calls != e.times:
+			t.Errorf("%s: expected %d calls, got %d", e.String(), e.times, e.calls)
+			ok = false
+		}
+	}
+	return ok
+}
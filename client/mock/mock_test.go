@@ -0,0 +1,77 @@
+package mock_test
+
+import (
+	"errors"
+	"github.com/clambin/go-metrics/client/mock"
+	"github.com/clambin/go-metrics/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCaller(t *testing.T) {
+	c := &mock.Caller{}
+	c.Expect(http.MethodGet, "/foo").ReturnJSON(http.StatusOK, map[string]int{"value": 42}).Once()
+	c.Expect(http.MethodGet, "/foo").Return(http.StatusNotFound, "gone")
+	c.Expect(http.MethodPost, "/bar").WithBody("hello").Return(http.StatusCreated, "created").ReturnHeader("X-Foo", "bar")
+	c.Expect("", "/down").ReturnError(errors.New("connection refused"))
+
+	resp, body := call(t, c, http.MethodGet, "/foo", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `{"value":42}`, body)
+
+	resp, body = call(t, c, http.MethodGet, "/foo", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "404 Not Found", resp.Status)
+	assert.Equal(t, "gone", body)
+
+	resp, body = call(t, c, http.MethodPost, "/bar", "hello")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "bar", resp.Header.Get("X-Foo"))
+	assert.Equal(t, "created", body)
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost/bar", strings.NewReader("world"))
+	_, err := c.Do(req)
+	assert.True(t, errors.Is(err, mock.ErrUnexpectedRequest))
+
+	req, _ = http.NewRequest(http.MethodDelete, "http://localhost/down", nil)
+	_, err = c.Do(req)
+	assert.EqualError(t, err, "connection refused")
+
+	requests := c.Requests()
+	require.Len(t, requests, 5)
+	assert.Equal(t, "http://localhost/bar", requests[2].URL)
+	assert.Equal(t, "hello", string(requests[2].Body))
+	assert.Equal(t, "world", string(requests[3].Body))
+
+	assert.True(t, c.AssertExpectations(t))
+}
+
+func TestCaller_AssertExpectations(t *testing.T) {
+	c := &mock.Caller{}
+	c.Expect(http.MethodGet, "/foo")
+	c.Expect("", "/bar").Times(2)
+	call(t, c, http.MethodGet, "/bar", "")
+
+	var f testutil.FakeT
+	assert.False(t, c.AssertExpectations(&f))
+	assert.Equal(t, []string{
+		"GET /foo: expected at least one call",
+		"* /bar: expected 2 calls, got 1",
+	}, f.Errors)
+}
+
+func call(t *testing.T, c *mock.Caller, method, path, body string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, "http://localhost"+path, strings.NewReader(body))
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	response, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(response)
+}
@@ -0,0 +1,75 @@
+package client
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter limits the rate at which calls are sent to its Caller, using a token bucket: Limit tokens are added
+// per second, up to Burst tokens. Each call takes one token, waiting for one to become available if needed.
+// If the request's context is cancelled while waiting, Do returns the context's error.
+type RateLimiter struct {
+	Caller
+	// Limit is the maximum number of calls per second. If zero (or negative), calls are not limited
+	Limit float64
+	// Burst is the maximum number of calls that can be sent at once. If zero, 1 is used
+	Burst  int
+	tokens float64
+	last   time.Time
+	lock   sync.Mutex
+}
+
+var _ Caller = &RateLimiter{}
+
+// Do waits until the rate limit allows the call, and then sends the request
+func (r *RateLimiter) Do(req *http.Request) (*http.Response, error) {
+	if r.Limit <= 0 {
+		return r.Caller.Do(req)
+	}
+	if wait := r.reserve(); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			r.cancel()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+	return r.Caller.Do(req)
+}
+
+// reserve takes a token and returns how long the caller must wait for the token to become available
+func (r *RateLimiter) reserve() time.Duration {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	burst := float64(r.Burst)
+	if burst == 0 {
+		burst = 1
+	}
+	now := time.Now()
+	if r.last.IsZero() {
+		r.tokens = burst
+	} else {
+		r.tokens += now.Sub(r.last).Seconds() * r.Limit
+		if r.tokens > burst {
+			r.tokens = burst
+		}
+	}
+	r.last = now
+
+	r.tokens--
+	if r.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-r.tokens / r.Limit * float64(time.Second))
+}
+
+// cancel returns a token that was reserved, but not used
+func (r *RateLimiter) cancel() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.tokens++
+}
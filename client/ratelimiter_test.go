@@ -0,0 +1,48 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/foo")
+	c := &client.RateLimiter{Caller: m, Limit: 20, Burst: 2}
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		_, err := call(c, http.MethodGet, "/foo", "")
+		require.NoError(t, err)
+	}
+	// first two calls use the burst. the next two wait 50 msec each
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/foo", nil)
+	_, err := c.Do(req)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Len(t, m.Requests(), 4)
+}
+
+func TestRateLimiter_Unlimited(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/foo")
+	c := &client.RateLimiter{Caller: m}
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		_, err := call(c, http.MethodGet, "/foo", "")
+		require.NoError(t, err)
+	}
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	assert.Len(t, m.Requests(), 10)
+}
@@ -0,0 +1,175 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/clambin/go-metrics/internal/sanitize"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"sync"
+)
+
+// Recorder records API calls to a cassette file, or replays them from the cassette, without network access.
+// In RecordMode, Recorder sends each request to its Caller and writes the request and its response to the cassette:
+//
+//	r := &client.Recorder{Caller: &client.BaseClient{}, Mode: client.RecordMode, Cassette: "testdata/api.json"}
+//
+// In ReplayMode, Recorder returns the recorded response for each request. Requests are matched on method, path,
+// query and body, so a cassette recorded against one host (e.g. an httptest.Server) can be replayed against another.
+// The values of sensitive query parameters (e.g. api_key or token) are redacted before the URL is written to the
+// cassette, and requests are matched on the redacted URL. Request headers aren't recorded, but the response's headers
+// are: make sure they hold no secrets before committing a cassette.
+// If a request was recorded several times, its responses are replayed in the order in which they were recorded.
+// Once all have been replayed, the last response is returned again. Requests without a recorded response fail with
+// ErrUnmatchedRequest.
+type Recorder struct {
+	// Caller sends the requests in RecordMode
+	Caller
+	// Mode determines whether Recorder records or replays requests
+	Mode RecorderMode
+	// Cassette is the name of the file holding the recorded requests
+	Cassette string
+	entries  []cassetteEntry
+	replayed []bool
+	loaded   bool
+	lock     sync.Mutex
+}
+
+var _ Caller = &Recorder{}
+
+// RecorderMode determines whether a Recorder records or replays requests
+type RecorderMode int
+
+const (
+	// ReplayMode replays requests from the cassette
+	ReplayMode RecorderMode = iota
+	// RecordMode sends requests to the Recorder's Caller and records them in the cassette
+	RecordMode
+)
+
+// ErrUnmatchedRequest is returned by a Recorder in ReplayMode when the cassette holds no response for a request
+var ErrUnmatchedRequest = errors.New("no recorded response for request")
+
+// cassetteEntry contains a recorded request. Response is in the format produced by httputil.DumpResponse.
+// Body and Response are stored base64-encoded, so binary content (e.g. gzip or images) is recorded unchanged.
+type cassetteEntry struct {
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	Body     []byte `json:"body,omitempty"`
+	Response []byte `json:"response"`
+}
+
+// Do records or replays the request, depending on the Recorder's Mode
+func (r *Recorder) Do(req *http.Request) (resp *http.Response, err error) {
+	var body []byte
+	if body, err = readRequestBody(req); err != nil {
+		return
+	}
+	if r.Mode == RecordMode {
+		return r.record(req, body)
+	}
+	return r.replay(req, body)
+}
+
+func (r *Recorder) record(req *http.Request, body []byte) (resp *http.Response, err error) {
+	if resp, err = r.Caller.Do(req); err != nil {
+		return
+	}
+	dump, err := httputil.DumpResponse(resp, true)
+	if err == nil {
+		err = r.write(cassetteEntry{Method: req.Method, URL: sanitize.URL(req.URL, sanitize.DefaultQueryParams), Body: body, Response: dump})
+	}
+	if err != nil {
+		_ = resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// write adds the entry to the cassette
+func (r *Recorder) write(entry cassetteEntry) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.entries = append(r.entries, entry)
+	content, err := json.MarshalIndent(r.entries, "", "  ")
+	if err == nil {
+		err = os.WriteFile(r.Cassette, content, 0644)
+	}
+	return err
+}
+
+func (r *Recorder) replay(req *http.Request, body []byte) (resp *http.Response, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if err = r.load(); err != nil {
+		return
+	}
+
+	uri := requestURI(req.URL)
+	match := -1
+	for index, entry := range r.entries {
+		if entry.Method != req.Method || entry.requestURI() != uri || !bytes.Equal(entry.Body, body) {
+			continue
+		}
+		match = index
+		if !r.replayed[index] {
+			break
+		}
+	}
+	if match == -1 {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL.String(), ErrUnmatchedRequest)
+	}
+	r.replayed[match] = true
+	return cachedResponse(r.entries[match].Response, req)
+}
+
+// requestURI returns the path and query of the recorded request, with sensitive query parameters redacted
+func (e cassetteEntry) requestURI() string {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return e.URL
+	}
+	return requestURI(u)
+}
+
+// requestURI returns the path and query of the URL, with sensitive query parameters redacted
+func requestURI(u *url.URL) string {
+	redacted, err := url.Parse(sanitize.URL(u, sanitize.DefaultQueryParams))
+	if err != nil {
+		return u.RequestURI()
+	}
+	return redacted.RequestURI()
+}
+
+func (r *Recorder) load() error {
+	if r.loaded {
+		return nil
+	}
+	content, err := os.ReadFile(r.Cassette)
+	if err != nil {
+		return fmt.Errorf("recorder: %w", err)
+	}
+	if err = json.Unmarshal(content, &r.entries); err != nil {
+		return fmt.Errorf("recorder: invalid cassette %s: %w", r.Cassette, err)
+	}
+	r.replayed = make([]bool, len(r.entries))
+	r.loaded = true
+	return nil
+}
+
+// readRequestBody reads the body of the request and replaces it, so it can be read again
+func readRequestBody(req *http.Request) (body []byte, err error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return
+	}
+	if body, err = io.ReadAll(req.Body); err == nil {
+		_ = req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return
+}
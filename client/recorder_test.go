@@ -0,0 +1,154 @@
+package client_test
+
+import (
+	"errors"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	s := stubserver.New(
+		stubserver.Route{Path: "/foo", JSON: testStruct{Name: "bar", Age: 42}},
+		stubserver.Route{Method: http.MethodPost, Path: "/bar", Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			body, _ := io.ReadAll(req.Body)
+			_, _ = w.Write([]byte("received " + string(body)))
+		})},
+	)
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	r := &client.Recorder{Caller: &client.BaseClient{}, Mode: client.RecordMode, Cassette: cassette}
+	response, err := doCall(r, s.URL+"/foo")
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Name: "bar", Age: 42}, response)
+	body, err := post(r, s.URL+"/bar", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "received hello", body)
+	body, err = post(r, s.URL+"/bar", "world")
+	require.NoError(t, err)
+	assert.Equal(t, "received world", body)
+	s.Close()
+
+	content, err := os.ReadFile(cassette)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), `"method": "POST"`)
+
+	// requests are matched without their host
+	r = &client.Recorder{Mode: client.ReplayMode, Cassette: cassette}
+	response, err = doCall(r, "http://localhost:12345/foo")
+	require.NoError(t, err)
+	assert.Equal(t, testStruct{Name: "bar", Age: 42}, response)
+	body, err = post(r, s.URL+"/bar", "world")
+	require.NoError(t, err)
+	assert.Equal(t, "received world", body)
+	body, err = post(r, s.URL+"/bar", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "received hello", body)
+
+	_, err = post(r, s.URL+"/bar", "snafu")
+	assert.True(t, errors.Is(err, client.ErrUnmatchedRequest))
+	_, err = doCall(r, s.URL+"/snafu")
+	assert.True(t, errors.Is(err, client.ErrUnmatchedRequest))
+
+	r = &client.Recorder{Mode: client.ReplayMode, Cassette: filepath.Join(t.TempDir(), "missing.json")}
+	_, err = doCall(r, s.URL+"/foo")
+	assert.Error(t, err)
+}
+
+func TestRecorder_Concurrent(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", JSON: testStruct{Name: "bar", Age: 42}, Latency: 200 * time.Millisecond})
+	defer s.Close()
+	r := &client.Recorder{Caller: &client.BaseClient{}, Mode: client.RecordMode, Cassette: filepath.Join(t.TempDir(), "cassette.json")}
+
+	// calls aren't serialized while they are recorded
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := doCall(r, s.URL+"/foo")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Less(t, time.Since(start), time.Second)
+
+	r = &client.Recorder{Mode: client.ReplayMode, Cassette: r.Cassette}
+	for i := 0; i < 5; i++ {
+		_, err := doCall(r, s.URL+"/foo")
+		require.NoError(t, err)
+	}
+}
+
+func TestRecorder_Binary(t *testing.T) {
+	content := "\x1f\x8b\xff\xfe\x00\x80"
+	s := stubserver.New(stubserver.Route{Method: http.MethodPost, Path: "/foo", Body: content})
+	defer s.Close()
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	r := &client.Recorder{Caller: &client.BaseClient{}, Mode: client.RecordMode, Cassette: cassette}
+	body, err := post(r, s.URL+"/foo", "\xff\x00")
+	require.NoError(t, err)
+	assert.Equal(t, content, body)
+
+	// binary bodies are replayed unchanged
+	r = &client.Recorder{Mode: client.ReplayMode, Cassette: cassette}
+	body, err = post(r, s.URL+"/foo", "\xff\x00")
+	require.NoError(t, err)
+	assert.Equal(t, content, body)
+}
+
+func TestRecorder_Redact(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "hello"})
+	defer s.Close()
+	cassette := filepath.Join(t.TempDir(), "cassette.json")
+
+	r := &client.Recorder{Caller: &client.BaseClient{}, Mode: client.RecordMode, Cassette: cassette}
+	_, body, err := get(r, http.MethodGet, s.URL+"/foo?api_key=secret&page=1")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", body)
+
+	content, err := os.ReadFile(cassette)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "secret")
+
+	// requests are matched on their redacted URL
+	r = &client.Recorder{Mode: client.ReplayMode, Cassette: cassette}
+	_, body, err = get(r, http.MethodGet, "http://localhost/foo?page=1&api_key=other")
+	require.NoError(t, err)
+	assert.Equal(t, "hello", body)
+	_, _, err = get(r, http.MethodGet, "http://localhost/foo?page=2&api_key=secret")
+	assert.ErrorIs(t, err, client.ErrUnmatchedRequest)
+}
+
+func TestRecorder_WriteFailure(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", Body: "hello"})
+	defer s.Close()
+	r := &client.Recorder{Caller: &client.BaseClient{}, Mode: client.RecordMode, Cassette: filepath.Join(t.TempDir(), "missing", "cassette.json")}
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/foo", nil)
+	resp, err := r.Do(req)
+	assert.Error(t, err)
+	assert.Nil(t, resp)
+}
+
+func post(c client.Caller, url, body string) (string, error) {
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	resp, err := c.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	response, err := io.ReadAll(resp.Body)
+	return string(response), err
+}
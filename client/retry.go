@@ -0,0 +1,149 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Retrier retries failed calls. By default, a call is retried if it returned an error, or if the response has
+// a 429 or 5xx status code, provided the request's method is idempotent. Requests with a body are only retried if
+// the request's GetBody function is set (as done by http.NewRequest for common body types).
+type Retrier struct {
+	Caller
+	Options RetryOptions
+}
+
+var _ Caller = &Retrier{}
+
+// RetryOptions contains options to alter Retrier behaviour
+type RetryOptions struct {
+	// MaxAttempts is the maximum number of attempts, including the first one. If zero, 3 is used
+	MaxAttempts int
+	// Backoff is the delay before the first retry. The delay doubles for each subsequent retry.
+	// If zero, 100 msec is used. If the response holds a Retry-After header, that delay is used instead
+	Backoff time.Duration
+	// MaxBackoff is the maximum delay between two attempts. If zero, 10 seconds is used
+	MaxBackoff time.Duration
+	// ShouldRetry determines whether a call should be retried. If nil, DefaultShouldRetry is used
+	ShouldRetry func(req *http.Request, resp *http.Response, err error) bool
+	// Budget is the maximum total duration of all attempts, including the delays between them. If zero, the
+	// attempts are only limited by the request's context
+	Budget time.Duration
+}
+
+// Do sends the request, retrying it if needed. If all attempts fail, Do returns the outcome of the last attempt.
+// Do doesn't retry the request if the delay before the next attempt would exceed the request's deadline (or Budget).
+func (r *Retrier) Do(req *http.Request) (*http.Response, error) {
+	if r.Options.Budget == 0 {
+		return r.do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), r.Options.Budget)
+	return withCancel(r.do, req.WithContext(ctx), cancel)
+}
+
+func (r *Retrier) do(req *http.Request) (resp *http.Response, err error) {
+	maxAttempts := r.Options.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = 3
+	}
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		maxAttempts = 1
+	}
+	backoff := r.Options.Backoff
+	if backoff == 0 {
+		backoff = 100 * time.Millisecond
+	}
+	maxBackoff := r.Options.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = 10 * time.Second
+	}
+	shouldRetry := r.Options.ShouldRetry
+	if shouldRetry == nil {
+		shouldRetry = DefaultShouldRetry
+	}
+
+	for attempt := 1; ; attempt++ {
+		next := req
+		if attempt > 1 && req.GetBody != nil {
+			// the caller's request must not be modified: send the new body on a copy
+			next = req.Clone(req.Context())
+			if next.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err = r.Caller.Do(reportAttempt(next, attempt))
+		if attempt == maxAttempts || !shouldRetry(req, resp, err) {
+			return
+		}
+
+		delay := backoff
+		if requested := retryAfter(resp, err); requested > delay {
+			delay = requested
+		}
+		if delay > maxBackoff {
+			delay = maxBackoff
+		}
+		if deadline, ok := req.Context().Deadline(); ok && time.Until(deadline) < delay {
+			return
+		}
+		if resp != nil {
+			_ = resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			// cap the backoff, so it can't overflow
+			backoff = maxBackoff
+		}
+	}
+}
+
+// retryAfter returns the delay requested by the response's Retry-After header. If a StatusChecker converted
+// the response into an *HTTPError, the delay is taken from the error.
+func retryAfter(resp *http.Response, err error) time.Duration {
+	if resp != nil {
+		return parseRetryAfter(resp.Header.Get("Retry-After"))
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.RetryAfter
+	}
+	return 0
+}
+
+// DefaultShouldRetry retries calls with an idempotent method that failed, or whose response has a 429 or 5xx
+// status code. An *HTTPError (as returned by a StatusChecker) is retried only if it has a 429 or 5xx status code.
+// Calls whose context was cancelled are not retried.
+func DefaultShouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Context().Err() != nil || !isIdempotent(req.Method) {
+		return false
+	}
+	statusCode := 0
+	if err != nil {
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			return true
+		}
+		statusCode = httpErr.StatusCode
+	} else {
+		statusCode = resp.StatusCode
+	}
+	return statusCode == http.StatusTooManyRequests || statusCode >= 500
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/mock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetrier(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/flaky").ReturnError(errors.New("connection refused")).Once()
+	m.Expect(http.MethodGet, "/flaky").Return(http.StatusServiceUnavailable, "").Once()
+	m.Expect(http.MethodGet, "/flaky").Return(http.StatusOK, "ok").Once()
+	m.Expect(http.MethodGet, "/down").Return(http.StatusBadGateway, "").Times(2)
+	m.Expect(http.MethodGet, "/notfound").Return(http.StatusNotFound, "").Once()
+	m.Expect(http.MethodPost, "/post").Return(http.StatusServiceUnavailable, "").Once()
+	m.Expect(http.MethodPut, "/put").WithBody("hello").Return(http.StatusServiceUnavailable, "").Once()
+	m.Expect(http.MethodPut, "/put").WithBody("hello").Return(http.StatusOK, "").Once()
+	c := &client.Retrier{Caller: m, Options: client.RetryOptions{Backoff: time.Millisecond}}
+
+	resp, err := call(c, http.MethodGet, "/flaky", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	c.Options.MaxAttempts = 2
+	resp, err = call(c, http.MethodGet, "/down", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+
+	resp, err = call(c, http.MethodGet, "/notfound", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = call(c, http.MethodPost, "/post", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	resp, err = call(c, http.MethodPut, "/put", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	m.AssertExpectations(t)
+}
+
+func TestRetrier_Body(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodPut, "/put").WithBody("hello").Return(http.StatusServiceUnavailable, "").Times(2)
+	m.Expect(http.MethodPut, "/put").WithBody("hello").Return(http.StatusOK, "").Once()
+	c := &client.Retrier{Caller: m, Options: client.RetryOptions{Backoff: time.Millisecond}}
+
+	// the body of each retry is set on a copy of the request
+	req, _ := http.NewRequest(http.MethodPut, "http://localhost/put", strings.NewReader("hello"))
+	body := req.Body
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.True(t, req.Body == body)
+}
+
+func TestRetrier_MaxBackoff(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/down").Return(http.StatusBadGateway, "")
+	c := &client.Retrier{Caller: m, Options: client.RetryOptions{MaxAttempts: 70, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}}
+
+	// the backoff doesn't overflow into negative delays
+	start := time.Now()
+	resp, err := call(c, http.MethodGet, "/down", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 69*time.Millisecond)
+}
+
+func TestRetrier_Context(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/throttled").Return(http.StatusTooManyRequests, "").ReturnHeader("Retry-After", "60")
+	c := &client.Retrier{Caller: m, Options: client.RetryOptions{Backoff: time.Millisecond, MaxBackoff: time.Hour}}
+
+	// the requested delay exceeds the request's deadline: Do returns the response without waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://localhost/throttled", nil)
+	start := time.Now()
+	resp, err := c.Do(req)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+	assert.Len(t, m.Requests(), 1)
+
+	// the request is cancelled while waiting for the next attempt
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel2()
+	}()
+	req, _ = http.NewRequestWithContext(ctx2, http.MethodGet, "http://localhost/throttled", nil)
+	start = time.Now()
+	_, err = c.Do(req)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, m.Requests(), 2)
+}
+
+func TestRetrier_StatusChecker(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/notfound").Return(http.StatusNotFound, "").Once()
+	m.Expect(http.MethodGet, "/throttled").Return(http.StatusTooManyRequests, "").ReturnHeader("Retry-After", "1").Once()
+	m.Expect(http.MethodGet, "/throttled").Return(http.StatusOK, "").Once()
+	c := &client.Retrier{Caller: &client.StatusChecker{Caller: m}, Options: client.RetryOptions{Backoff: time.Millisecond}}
+
+	_, err := call(c, http.MethodGet, "/notfound", "")
+	assert.ErrorIs(t, err, client.ErrClientError)
+
+	// the Retry-After delay of the *HTTPError is honoured
+	start := time.Now()
+	resp, err := call(c, http.MethodGet, "/throttled", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), time.Second)
+
+	m.AssertExpectations(t)
+}
+
+func call(c client.Caller, method, path, body string) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != "" {
+		reqBody = strings.NewReader(body)
+	}
+	req, _ := http.NewRequest(method, "http://localhost"+path, reqBody)
+	return c.Do(req)
+}
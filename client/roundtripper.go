@@ -0,0 +1,70 @@
+package client
+
+import (
+	"github.com/clambin/cache"
+	"net/http"
+	"time"
+)
+
+// CallerRoundTripper turns a Caller into an http.RoundTripper. Use this to plug a Caller chain into
+// libraries that accept an *http.Client:
+//
+//	httpClient := &http.Client{Transport: &client.CallerRoundTripper{Caller: c}}
+type CallerRoundTripper struct {
+	Caller
+}
+
+var _ http.RoundTripper = &CallerRoundTripper{}
+
+// RoundTrip implements the http.RoundTripper interface
+func (c *CallerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return c.Caller.Do(req)
+}
+
+// RoundTripperCaller turns an http.RoundTripper into a Caller. Contrary to BaseClient, it sends each request
+// directly to the RoundTripper, i.e. it doesn't follow redirects or handle cookies. If RoundTripper is nil,
+// http.DefaultTransport is used.
+type RoundTripperCaller struct {
+	RoundTripper http.RoundTripper
+}
+
+var _ Caller = &RoundTripperCaller{}
+
+// Do sends the request to the RoundTripper
+func (r *RoundTripperCaller) Do(req *http.Request) (*http.Response, error) {
+	roundTripper := r.RoundTripper
+	if roundTripper == nil {
+		roundTripper = http.DefaultTransport
+	}
+	return roundTripper.RoundTrip(req)
+}
+
+// InstrumentedRoundTripper is the http.RoundTripper equivalent of InstrumentedClient. It records performance
+// metrics of each request, before sending it to the Next RoundTripper. If Next is nil, http.DefaultTransport is used:
+//
+//	httpClient := &http.Client{Transport: &client.InstrumentedRoundTripper{
+//		Application: "foo",
+//		Options:     client.Options{PrometheusMetrics: client.NewMetrics("foo", "")},
+//	}}
+type InstrumentedRoundTripper struct {
+	Next        http.RoundTripper
+	Options     Options
+	Application string
+}
+
+var _ http.RoundTripper = &InstrumentedRoundTripper{}
+
+// RoundTrip implements the http.RoundTripper interface
+func (i *InstrumentedRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	return i.Options.call(&RoundTripperCaller{RoundTripper: i.Next}, i.Application, req)
+}
+
+// NewCachingRoundTripper is the http.RoundTripper equivalent of a Cacher. It caches the responses of the Next
+// RoundTripper, as determined by cacheEntries. If next is nil, http.DefaultTransport is used.
+func NewCachingRoundTripper(next http.RoundTripper, cacheEntries []CacheTableEntry, cacheExpiry, cacheCleanup time.Duration) http.RoundTripper {
+	return &CallerRoundTripper{Caller: &Cacher{
+		Caller: &RoundTripperCaller{RoundTripper: next},
+		Table:  CacheTable{Table: cacheEntries},
+		Cache:  cache.New[string, []byte](cacheExpiry, cacheCleanup),
+	}}
+}
@@ -0,0 +1,66 @@
+package client_test
+
+import (
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/mock"
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCallerRoundTripper(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/foo").ReturnJSON(http.StatusOK, testStruct{Name: "foo", Age: 42})
+
+	c := &client.BaseClient{HTTPClient: &http.Client{Transport: &client.CallerRoundTripper{Caller: m}}}
+	response, err := doCall(c, "http://localhost/foo")
+	require.NoError(t, err)
+	assert.Equal(t, "foo", response.Name)
+	m.AssertExpectations(t)
+}
+
+func TestInstrumentedRoundTripper(t *testing.T) {
+	s := stubserver.New(
+		stubserver.Route{Path: "/foo", JSON: testStruct{Name: "foo", Age: 42}},
+		stubserver.Route{Path: "/redirect", StatusCode: http.StatusFound, Header: http.Header{"Location": []string{"/foo"}}},
+	)
+	defer s.Close()
+	metrics := client.NewMetrics("roundtripper", "")
+
+	httpClient := &http.Client{Transport: &client.InstrumentedRoundTripper{
+		Application: "foo",
+		Options:     client.Options{PrometheusMetrics: metrics},
+	}}
+	c := &client.BaseClient{HTTPClient: httpClient}
+
+	_, err := doCall(c, s.URL+"/redirect")
+	require.NoError(t, err)
+
+	latency, err := tools.Collect(metrics.Latency)
+	require.NoError(t, err)
+	for _, endpoint := range []string{"/foo", "/redirect"} {
+		summary, err := latency.Summary("roundtripper_api_latency", prometheus.Labels{"application": "foo", "endpoint": endpoint, "method": http.MethodGet})
+		require.NoError(t, err, endpoint)
+		assert.Equal(t, uint64(1), summary.GetSampleCount(), endpoint)
+	}
+}
+
+func TestNewCachingRoundTripper(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/foo", JSON: testStruct{Name: "foo", Age: 42}})
+	defer s.Close()
+
+	c := &client.BaseClient{HTTPClient: &http.Client{
+		Transport: client.NewCachingRoundTripper(nil, []client.CacheTableEntry{{Endpoint: "/foo"}}, time.Minute, 0),
+	}}
+	for i := 0; i < 3; i++ {
+		response, err := doCall(c, s.URL+"/foo")
+		require.NoError(t, err)
+		assert.Equal(t, "foo", response.Name)
+	}
+	s.AssertCalls(t, http.MethodGet, "/foo", 1)
+}
@@ -0,0 +1,19 @@
+/*
+Package stubserver provides an HTTP server with canned responses, to test API clients without depending on the real API.
+
+Each Route declares the response for an endpoint, with optional latency and failure injection:
+
+	s := stubserver.New(
+		stubserver.Route{Path: "/foo", JSON: map[string]int{"value": 42}},
+		stubserver.Route{Path: "/slow", Body: "ok", Latency: 100 * time.Millisecond},
+		stubserver.Route{Path: "/flaky", Body: "ok", FailEvery: 2},
+	)
+	defer s.Close()
+
+The Server records every call it receives, so tests can verify how often the client called each endpoint:
+
+	c := client.NewCacher(nil, "foo", client.Options{}, []client.CacheTableEntry{{Endpoint: "/foo"}}, time.Minute, 0)
+	// call s.URL + "/foo" twice
+	s.AssertCalls(t, http.MethodGet, "/foo", 1)
+*/
+package stubserver
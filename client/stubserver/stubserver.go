@@ -0,0 +1,175 @@
+package stubserver
+
+import (
+	"encoding/json"
+	"github.com/clambin/go-metrics/tools"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// Server is an HTTP server that serves canned responses for a set of Routes. It records how many times each
+// endpoint was called, so tests can verify how a client behaves (e.g. whether it caches responses).
+type Server struct {
+	*httptest.Server
+	routes []*Route
+	calls  map[string]int
+	lock   sync.Mutex
+}
+
+// Route contains the canned response for a single endpoint
+type Route struct {
+	// Method of the route. If empty, the route matches any method
+	Method string
+	// Path of the route. Must match the request's URL Path exactly
+	Path string
+	// StatusCode of the response. If zero, http.StatusOK is used
+	StatusCode int
+	// Header contains the headers of the response
+	Header http.Header
+	// Body of the response
+	Body string
+	// JSON contains a value to be returned as the response's body, in JSON format. Overrides Body
+	JSON interface{}
+	// Latency delays the response by the specified duration
+	Latency time.Duration
+	// FailFirst fails the first FailFirst calls to the route
+	FailFirst int
+	// FailEvery fails every FailEvery-th call to the route
+	FailEvery int
+	// FailStatusCode is the status code of a failed call. If zero, http.StatusInternalServerError is used
+	FailStatusCode int
+	// Handler handles the request, instead of returning the canned response
+	Handler http.Handler
+	calls   int
+}
+
+// New creates and starts a new Server for the provided Routes. Call Close when done:
+//
+//	s := stubserver.New(stubserver.Route{Path: "/foo", JSON: response})
+//	defer s.Close()
+//	// call s.URL + "/foo"
+//	assert.Equal(t, 1, s.Calls(http.MethodGet, "/foo"))
+func New(routes ...Route) *Server {
+	s := &Server{calls: make(map[string]int)}
+	for index := range routes {
+		s.routes = append(s.routes, &routes[index])
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+func (s *Server) handle(w http.ResponseWriter, req *http.Request) {
+	s.lock.Lock()
+	s.calls[callKey(req.Method, req.URL.Path)]++
+	route := s.match(req)
+	var call int
+	if route != nil {
+		route.calls++
+		call = route.calls
+	}
+	s.lock.Unlock()
+
+	if route == nil {
+		http.Error(w, "invalid endpoint: "+req.URL.Path, http.StatusNotFound)
+		return
+	}
+	route.serve(w, req, call)
+}
+
+func (s *Server) match(req *http.Request) *Route {
+	for _, route := range s.routes {
+		if route.Path == req.URL.Path && (route.Method == "" || route.Method == req.Method) {
+			return route
+		}
+	}
+	return nil
+}
+
+func (r *Route) serve(w http.ResponseWriter, req *http.Request, call int) {
+	if r.Latency > 0 {
+		select {
+		case <-time.After(r.Latency):
+		case <-req.Context().Done():
+			return
+		}
+	}
+
+	if call <= r.FailFirst || (r.FailEvery > 0 && call%r.FailEvery == 0) {
+		statusCode := r.FailStatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusInternalServerError
+		}
+		http.Error(w, "injected failure", statusCode)
+		return
+	}
+
+	if r.Handler != nil {
+		r.Handler.ServeHTTP(w, req)
+		return
+	}
+
+	for key, values := range r.Header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	body := []byte(r.Body)
+	if r.JSON != nil {
+		var err error
+		if body, err = json.Marshal(r.JSON); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+	}
+	if r.StatusCode != 0 {
+		w.WriteHeader(r.StatusCode)
+	}
+	_, _ = w.Write(body)
+}
+
+// Calls returns the number of times the endpoint was called with the specified method. This includes calls
+// to endpoints without a matching Route.
+func (s *Server) Calls(method, path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.calls[callKey(method, path)]
+}
+
+// TotalCalls returns the total number of calls received by the Server
+func (s *Server) TotalCalls() (total int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, calls := range s.calls {
+		total += calls
+	}
+	return
+}
+
+// Reset clears all call counters
+func (s *Server) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.calls = make(map[string]int)
+	for _, route := range s.routes {
+		route.calls = 0
+	}
+}
+
+// AssertCalls reports a test failure if the endpoint wasn't called the expected number of times with the specified method
+func (s *Server) AssertCalls(t tools.TestingT, method, path string, expected int) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if calls := s.Calls(method, path); calls != expected {
+		t.Errorf("%s: expected %d calls, got %d", callKey(method, path), expected, calls)
+		return false
+	}
+	return true
+}
+
+func callKey(method, path string) string {
+	return method + " " + path
+}
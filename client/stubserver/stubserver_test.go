@@ -0,0 +1,79 @@
+package stubserver_test
+
+import (
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/clambin/go-metrics/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	s := stubserver.New(
+		stubserver.Route{Path: "/foo", JSON: map[string]int{"value": 42}},
+		stubserver.Route{Method: http.MethodPost, Path: "/bar", StatusCode: http.StatusCreated, Body: "created", Header: http.Header{"X-Foo": []string{"bar"}}},
+		stubserver.Route{Path: "/flaky", Body: "ok", FailFirst: 1, FailEvery: 3, FailStatusCode: http.StatusBadGateway},
+		stubserver.Route{Path: "/slow", Body: "ok", Latency: 50 * time.Millisecond},
+		stubserver.Route{Path: "/custom", Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		})},
+	)
+	defer s.Close()
+
+	resp, body := call(t, http.MethodGet, s.URL+"/foo")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `{"value":42}`, body)
+
+	resp, body = call(t, http.MethodPost, s.URL+"/bar")
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	assert.Equal(t, "bar", resp.Header.Get("X-Foo"))
+	assert.Equal(t, "created", body)
+
+	resp, _ = call(t, http.MethodGet, s.URL+"/bar")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	var statusCodes []int
+	for i := 0; i < 4; i++ {
+		resp, _ = call(t, http.MethodGet, s.URL+"/flaky")
+		statusCodes = append(statusCodes, resp.StatusCode)
+	}
+	assert.Equal(t, []int{http.StatusBadGateway, http.StatusOK, http.StatusBadGateway, http.StatusOK}, statusCodes)
+
+	start := time.Now()
+	resp, _ = call(t, http.MethodGet, s.URL+"/slow")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+
+	resp, _ = call(t, http.MethodGet, s.URL+"/custom")
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	assert.True(t, s.AssertCalls(t, http.MethodGet, "/foo", 1))
+	assert.True(t, s.AssertCalls(t, http.MethodPost, "/bar", 1))
+	assert.True(t, s.AssertCalls(t, http.MethodGet, "/bar", 1))
+	assert.True(t, s.AssertCalls(t, http.MethodGet, "/flaky", 4))
+	assert.Equal(t, 9, s.TotalCalls())
+
+	var f testutil.FakeT
+	assert.False(t, s.AssertCalls(&f, http.MethodGet, "/foo", 2))
+	assert.Equal(t, []string{"GET /foo: expected 2 calls, got 1"}, f.Errors)
+
+	s.Reset()
+	assert.Zero(t, s.TotalCalls())
+	resp, _ = call(t, http.MethodGet, s.URL+"/flaky")
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+}
+
+func call(t *testing.T, method, url string) (*http.Response, string) {
+	t.Helper()
+	req, _ := http.NewRequest(method, url, nil)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body)
+}
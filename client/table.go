@@ -42,11 +42,7 @@ func (c *CacheTable) compileIfNeeded() {
 
 	for index := range c.Table {
 		if c.Table[index].IsRegExp {
-			var err error
-			c.Table[index].compiledRegExp, err = regexp.Compile(c.Table[index].Endpoint)
-			if err != nil {
-				panic(fmt.Errorf("cacheTable: invalid regexp '%s': %w", c.Table[index].Endpoint, err))
-			}
+			c.Table[index].compiledRegExp = compileEndpoint("cacheTable", c.Table[index].Endpoint)
 		}
 	}
 	c.compiled = true
@@ -82,18 +78,36 @@ func (entry CacheTableEntry) shouldCache(r *http.Request) (match bool, expiry ti
 }
 
 func (entry CacheTableEntry) matchesEndpoint(r *http.Request) bool {
-	endpoint := r.URL.Path
-	if entry.IsRegExp {
-		return entry.compiledRegExp.MatchString(endpoint)
-	}
-	return entry.Endpoint == endpoint
+	return matchesEndpoint(entry.Endpoint, entry.compiledRegExp, r)
 }
 
 func (entry CacheTableEntry) matchesMethods(r *http.Request) bool {
-	if len(entry.Methods) == 0 {
+	return matchesMethods(entry.Methods, r)
+}
+
+// compileEndpoint compiles an endpoint's regular expression. It panics if the regular expression is invalid.
+func compileEndpoint(table, endpoint string) *regexp.Regexp {
+	re, err := regexp.Compile(endpoint)
+	if err != nil {
+		panic(fmt.Errorf("%s: invalid regexp '%s': %w", table, endpoint, err))
+	}
+	return re
+}
+
+// matchesEndpoint checks if the request's path matches the endpoint. If compiledRegExp is set, it is used instead.
+func matchesEndpoint(endpoint string, compiledRegExp *regexp.Regexp, r *http.Request) bool {
+	if compiledRegExp != nil {
+		return compiledRegExp.MatchString(r.URL.Path)
+	}
+	return endpoint == r.URL.Path
+}
+
+// matchesMethods checks if the request's method is in methods. If methods is empty, any method matches.
+func matchesMethods(methods []string, r *http.Request) bool {
+	if len(methods) == 0 {
 		return true
 	}
-	for _, method := range entry.Methods {
+	for _, method := range methods {
 		if method == r.Method {
 			return true
 		}
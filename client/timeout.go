@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// TimeoutCaller applies a timeout to each request, as determined by its TimeoutTable:
+//
+//	c := &client.TimeoutCaller{
+//		Caller: &client.BaseClient{},
+//		Table: client.TimeoutTable{Table: []client.TimeoutTableEntry{
+//			{Endpoint: "/reports", Timeout: time.Minute},
+//		}},
+//		DefaultTimeout: 5 * time.Second,
+//	}
+//
+// The timeout is set as a deadline on the request's context, so it covers the call and reading the response's body.
+// The deadline is released when the body is closed. If the request's context already has an earlier deadline,
+// that deadline applies.
+type TimeoutCaller struct {
+	Caller
+	// Table holds the timeouts for each endpoint
+	Table TimeoutTable
+	// DefaultTimeout is the timeout of requests that don't match any entry in Table. If zero, no timeout is applied
+	DefaultTimeout time.Duration
+}
+
+var _ Caller = &TimeoutCaller{}
+
+// Do sends the request with the timeout of the request's endpoint
+func (t *TimeoutCaller) Do(req *http.Request) (*http.Response, error) {
+	timeout, found := t.Table.timeout(req)
+	if !found {
+		timeout = t.DefaultTimeout
+	}
+	if timeout == 0 {
+		return t.Caller.Do(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	return withCancel(t.Caller.Do, req.WithContext(ctx), cancel)
+}
+
+// withCancel sends the request with do. Once the call fails, or the response's body is closed, cancel is called.
+func withCancel(do func(*http.Request) (*http.Response, error), req *http.Request, cancel context.CancelFunc) (resp *http.Response, err error) {
+	if resp, err = do(req); err != nil || resp.Body == nil {
+		cancel()
+		return
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return
+}
+
+// cancelOnClose cancels a request's context when the response's body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+// TimeoutTable holds the timeouts of a set of endpoints. The first matching entry determines the timeout.
+type TimeoutTable struct {
+	Table    []TimeoutTableEntry
+	compiled bool
+	lock     sync.Mutex
+}
+
+// TimeoutTableEntry contains the timeout of a single endpoint. Endpoints are matched like CacheTableEntry:
+// if the Endpoint is a regular expression, IsRegExp must be set. TimeoutTable will panic if the regular expression is invalid.
+type TimeoutTableEntry struct {
+	// Endpoint is the URL Path of the requests. Can be a literal path, or a regular expression.
+	// In the latter case, set IsRegExp to true
+	Endpoint string
+	// Methods is the list of HTTP Methods of the requests. If empty, requests for any method match.
+	Methods []string
+	// IsRegExp indicated the Endpoint is a regular expression.
+	IsRegExp bool
+	// Timeout of the requests
+	Timeout        time.Duration
+	compiledRegExp *regexp.Regexp
+}
+
+func (t *TimeoutTable) timeout(r *http.Request) (time.Duration, bool) {
+	t.compileIfNeeded()
+	for _, entry := range t.Table {
+		if matchesEndpoint(entry.Endpoint, entry.compiledRegExp, r) && matchesMethods(entry.Methods, r) {
+			return entry.Timeout, true
+		}
+	}
+	return 0, false
+}
+
+func (t *TimeoutTable) compileIfNeeded() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.compiled {
+		return
+	}
+	for index := range t.Table {
+		if t.Table[index].IsRegExp {
+			t.Table[index].compiledRegExp = compileEndpoint("timeoutTable", t.Table[index].Endpoint)
+		}
+	}
+	t.compiled = true
+}
+
+// IsTimeout reports whether the error was caused by a timeout, i.e. an expired context deadline or a network timeout
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// WithTimeouts applies per-endpoint timeouts, like TimeoutCaller
+func WithTimeouts(table []TimeoutTableEntry, defaultTimeout time.Duration) Middleware {
+	return Middleware{
+		Name: "timeout",
+		Wrap: func(next Caller) Caller {
+			return &TimeoutCaller{Caller: next, Table: TimeoutTable{Table: table}, DefaultTimeout: defaultTimeout}
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package client_test
+
+import (
+	"context"
+	"github.com/clambin/go-metrics/client"
+	"github.com/clambin/go-metrics/client/mock"
+	"github.com/clambin/go-metrics/client/stubserver"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestTimeoutCaller(t *testing.T) {
+	s := stubserver.New(
+		stubserver.Route{Path: "/slow", Body: "slow", Latency: 200 * time.Millisecond},
+		stubserver.Route{Path: "/reports/1", Body: "report", Latency: 200 * time.Millisecond},
+		stubserver.Route{Path: "/fast", Body: "fast"},
+	)
+	defer s.Close()
+
+	c := &client.TimeoutCaller{
+		Caller: &client.BaseClient{},
+		Table: client.TimeoutTable{Table: []client.TimeoutTableEntry{
+			{Endpoint: "/slow", Timeout: 20 * time.Millisecond},
+			{Endpoint: "/reports/.+", IsRegExp: true, Timeout: time.Second},
+			{Endpoint: "/fast", Methods: []string{http.MethodPost}, Timeout: time.Nanosecond},
+		}},
+		DefaultTimeout: 50 * time.Millisecond,
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/slow", nil)
+	_, err := c.Do(req)
+	require.Error(t, err)
+	assert.True(t, client.IsTimeout(err))
+
+	for _, path := range []string{"/reports/1", "/fast"} {
+		req, _ = http.NewRequest(http.MethodGet, s.URL+path, nil)
+		resp, err := c.Do(req)
+		require.NoError(t, err, path)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err, path)
+		assert.NotEmpty(t, body, path)
+		require.NoError(t, resp.Body.Close())
+	}
+}
+
+func TestTimeoutCaller_Metrics(t *testing.T) {
+	s := stubserver.New(stubserver.Route{Path: "/slow", Latency: 200 * time.Millisecond})
+	defer s.Close()
+	metrics := client.NewMetrics("timeout", "")
+	c := client.Build(nil,
+		client.WithInstrumentation("foo", client.Options{PrometheusMetrics: metrics}),
+		client.WithTimeouts(nil, 20*time.Millisecond),
+	)
+
+	req, _ := http.NewRequest(http.MethodGet, s.URL+"/slow", nil)
+	_, err := c.Do(req)
+	require.Error(t, err)
+
+	labels := prometheus.Labels{"application": "foo", "endpoint": "/slow", "method": http.MethodGet}
+	m, err := tools.Collect(metrics.Timeouts)
+	require.NoError(t, err)
+	value, err := m.Counter("timeout_api_timeouts_total", labels)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+
+	m, err = tools.Collect(metrics.Errors)
+	require.NoError(t, err)
+	value, err = m.Counter("timeout_api_errors_total", labels)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+}
+
+func TestRetrier_Budget(t *testing.T) {
+	m := &mock.Caller{}
+	m.Expect(http.MethodGet, "/down").Return(http.StatusServiceUnavailable, "")
+	c := &client.Retrier{Caller: m, Options: client.RetryOptions{MaxAttempts: 10, Backoff: 40 * time.Millisecond, Budget: 100 * time.Millisecond}}
+
+	start := time.Now()
+	resp, err := call(c, http.MethodGet, "/down", "")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+	// 1st attempt, 40 msec delay, 2nd attempt. The next delay (80 msec) exceeds the remaining budget
+	assert.Len(t, m.Requests(), 2)
+
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Equal(t, context.Canceled, resp.Request.Context().Err())
+}
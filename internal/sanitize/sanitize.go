@@ -0,0 +1,75 @@
+// Package sanitize removes sensitive or high-cardinality information from requests before they are logged or
+// recorded in metrics.
+package sanitize
+
+import (
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the value of any redacted header or query parameter
+const Redacted = "REDACTED"
+
+// DefaultHeaders contains the headers that are redacted by default
+var DefaultHeaders = []string{"Authorization", "Cookie", "Proxy-Authorization", "Set-Cookie", "X-Api-Key"}
+
+// DefaultQueryParams contains the query parameters that are redacted by default
+var DefaultQueryParams = []string{"access_token", "api_key", "apikey", "key", "password", "secret", "token"}
+
+var idSegment = regexp.MustCompile(`^(\d+|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|[0-9a-fA-F]{16,})$`)
+
+// Path replaces any path segments that look like identifiers (numbers, UUIDs or long hexadecimal strings) with ":id"
+func Path(path string) string {
+	segments := strings.Split(path, "/")
+	for index, segment := range segments {
+		if idSegment.MatchString(segment) {
+			segments[index] = ":id"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// URL returns the URL as a string, with the values of the specified query parameters redacted.
+// Query parameters are matched case-insensitively. Any user password is redacted as well.
+func URL(u *url.URL, params []string) string {
+	redacted := *u
+	if _, ok := u.User.Password(); ok {
+		redacted.User = url.UserPassword(u.User.Username(), Redacted)
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		for key, values := range query {
+			if contains(params, key) {
+				for index := range values {
+					values[index] = Redacted
+				}
+			}
+		}
+		redacted.RawQuery = query.Encode()
+	}
+	return redacted.String()
+}
+
+// Header returns a copy of the headers, with the values of the specified headers redacted
+func Header(header http.Header, names []string) http.Header {
+	redacted := header.Clone()
+	for key, values := range redacted {
+		if contains(names, key) {
+			for index := range values {
+				values[index] = Redacted
+			}
+		}
+	}
+	return redacted
+}
+
+func contains(names []string, name string) bool {
+	for _, entry := range names {
+		if strings.EqualFold(entry, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+// Package testutil contains helpers for the module's own tests.
+package testutil
+
+import (
+	"fmt"
+	"github.com/clambin/go-metrics/tools"
+)
+
+// FakeT is a tools.TestingT that records the failures reported to it, so tests can verify the output of a test helper
+type FakeT struct {
+	// Errors contains the failures reported with Errorf, in the order in which they were reported
+	Errors []string
+}
+
+var _ tools.TestingT = &FakeT{}
+
+// Errorf records a failure
+func (f *FakeT) Errorf(format string, args ...interface{}) {
+	f.Errors = append(f.Errors, fmt.Sprintf(format, args...))
+}
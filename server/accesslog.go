@@ -0,0 +1,110 @@
+package server
+
+import (
+	"github.com/clambin/go-metrics/internal/sanitize"
+	"github.com/gorilla/mux"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// AccessLogOptions contains options to alter the behaviour of the middleware created by NewAccessLogMiddleware
+type AccessLogOptions struct {
+	// Logger receives the access log records. If nil, slog.Default() is used
+	Logger *slog.Logger
+	// Level determines the level of the record for a response's status code. If nil, DefaultAccessLogLevel is used
+	Level func(statusCode int) slog.Level
+	// Sample logs only one in every Sample successful (i.e. non-4xx/5xx) requests. If zero, all requests are logged
+	Sample int
+	// RouteName determines the route of a request. If nil, the route's path template (gorilla/mux) or the request's
+	// Pattern (http.ServeMux) is used. If no route name is found, the request's normalised path is used
+	RouteName RouteNameFunc
+	// LogHeaders adds the request's headers to each record
+	LogHeaders bool
+	// RedactHeaders contains the headers whose value is not logged. If nil, a default set is used
+	RedactHeaders []string
+	// RedactQueryParams contains the query parameters whose value is not logged. If nil, a default set is used
+	RedactQueryParams []string
+}
+
+// DefaultAccessLogLevel logs 5xx responses at Error level, 4xx responses at Warn level and all others at Info level
+func DefaultAccessLogLevel(statusCode int) slog.Level {
+	switch {
+	case statusCode >= 500:
+		return slog.LevelError
+	case statusCode >= 400:
+		return slog.LevelWarn
+	}
+	return slog.LevelInfo
+}
+
+// NewAccessLogMiddleware returns an HTTP middleware that logs each request with log/slog:
+//
+//	r := mux.NewRouter()
+//	r.Use(server.NewAccessLogMiddleware(server.AccessLogOptions{Logger: logger}))
+//
+// Each record holds the request's method, route, path, status code, duration, response size and remote address.
+// Sensitive headers and query parameters are redacted. Server adds the middleware to each listener when
+// Options.AccessLog is set.
+func NewAccessLogMiddleware(options AccessLogOptions) func(next http.Handler) http.Handler {
+	if options.Level == nil {
+		options.Level = DefaultAccessLogLevel
+	}
+	if options.RedactHeaders == nil {
+		options.RedactHeaders = sanitize.DefaultHeaders
+	}
+	if options.RedactQueryParams == nil {
+		options.RedactQueryParams = sanitize.DefaultQueryParams
+	}
+	var requests atomic.Uint64
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lrw := newLoggingResponseWriter(w)
+			start := time.Now()
+			next.ServeHTTP(lrw, r)
+			duration := time.Since(start)
+
+			level := options.Level(lrw.statusCode)
+			if lrw.statusCode < 400 && options.Sample > 1 && (requests.Add(1)-1)%uint64(options.Sample) != 0 {
+				return
+			}
+			logger := options.Logger
+			if logger == nil {
+				logger = slog.Default()
+			}
+			if !logger.Enabled(r.Context(), level) {
+				return
+			}
+
+			attrs := []slog.Attr{
+				slog.String("method", r.Method),
+				slog.String("route", options.routeName(r)),
+				slog.String("path", sanitize.URL(r.URL, options.RedactQueryParams)),
+				slog.Int("status", lrw.statusCode),
+				slog.Duration("duration", duration),
+				slog.Int("bytes", lrw.bytes),
+				slog.String("remote", r.RemoteAddr),
+			}
+			if options.LogHeaders {
+				attrs = append(attrs, slog.Any("headers", sanitize.Header(r.Header, options.RedactHeaders)))
+			}
+			logger.LogAttrs(r.Context(), level, "http request", attrs...)
+		})
+	}
+}
+
+func (o AccessLogOptions) routeName(r *http.Request) (name string) {
+	if o.RouteName != nil {
+		name = o.RouteName(r)
+	} else if route := mux.CurrentRoute(r); route != nil {
+		name, _ = route.GetPathTemplate()
+	} else {
+		name = patternRouteName(r)
+	}
+	if name == "" {
+		name = sanitize.Path(r.URL.Path)
+	}
+	return
+}
@@ -0,0 +1,108 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/clambin/go-metrics/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewAccessLogMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	m := http.NewServeMux()
+	m.HandleFunc("GET /users/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("hello!"))
+	})
+	h := server.NewAccessLogMiddleware(server.AccessLogOptions{
+		Logger:     slog.New(slog.NewJSONHandler(&buf, nil)),
+		LogHeaders: true,
+	})(m)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/123?token=secret&page=1", nil)
+	req.Header.Set("Authorization", "Bearer 123")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/notfound/42", nil))
+
+	records := accessLogRecords(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, "INFO", records[0]["level"])
+	assert.Equal(t, "GET", records[0]["method"])
+	assert.Equal(t, "/users/{id}", records[0]["route"])
+	assert.Equal(t, "/users/123?page=1&token=REDACTED", records[0]["path"])
+	assert.Equal(t, 200.0, records[0]["status"])
+	assert.Equal(t, 6.0, records[0]["bytes"])
+	assert.Equal(t, map[string]interface{}{"Authorization": []interface{}{"REDACTED"}}, records[0]["headers"])
+	assert.Equal(t, "WARN", records[1]["level"])
+	assert.Equal(t, "/notfound/:id", records[1]["route"])
+	assert.Equal(t, 404.0, records[1]["status"])
+}
+
+func TestNewAccessLogMiddleware_Sample(t *testing.T) {
+	var buf bytes.Buffer
+	h := server.NewAccessLogMiddleware(server.AccessLogOptions{
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		Sample: 5,
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	for i := 0; i < 10; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/ok", nil))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	}
+
+	var ok, failed int
+	for _, record := range accessLogRecords(t, &buf) {
+		switch record["status"] {
+		case 200.0:
+			ok++
+		case 500.0:
+			failed++
+			assert.Equal(t, "ERROR", record["level"])
+		}
+	}
+	assert.Equal(t, 2, ok)
+	assert.Equal(t, 10, failed)
+}
+
+func TestServer_AccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	s := server.NewWithOptions(0, server.Options{
+		Handlers:  []server.Handler{{Path: "/foo/{id}", Handler: http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {})}},
+		AccessLog: &server.AccessLogOptions{Logger: slog.New(slog.NewJSONHandler(&buf, nil))},
+	})
+	go func() {
+		_ = s.Run()
+	}()
+
+	_, err := httpGet(fmt.Sprintf("http://127.0.0.1:%d/foo/12", s.Port))
+	require.NoError(t, err)
+	require.NoError(t, s.Shutdown(time.Second))
+
+	records := accessLogRecords(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "/foo/{id}", records[0]["route"])
+}
+
+func accessLogRecords(t *testing.T, buf *bytes.Buffer) (records []map[string]interface{}) {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return
+}
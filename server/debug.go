@@ -0,0 +1,47 @@
+package server
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	runtimePprof "runtime/pprof"
+)
+
+// DebugOptions configures the pprof and expvar endpoints of a Server. With the default Prefix, the endpoints are:
+//
+//	/debug/pprof/          index of the available profiles
+//	/debug/pprof/<profile> the named profile (e.g. heap, goroutine, profile, trace)
+//	/debug/vars            expvar variables, in JSON format
+//
+// Requests to the debug endpoints aren't measured in the "http_duration_seconds" metric, whichever listener serves them.
+type DebugOptions struct {
+	// Prefix of the debug endpoints. If empty, "/debug" is used
+	Prefix string
+	// Separate serves the debug endpoints on a separate listener, on Port, rather than on the Server's Port.
+	// This avoids exposing them alongside the /metrics endpoint
+	Separate bool
+	// Port of the separate listener. If zero, a randomly chosen free port is used. The selected port can be found
+	// in Server's DebugPort field. Only valid if Separate is set: NewWithOptions panics otherwise
+	Port int
+}
+
+func (o DebugOptions) handlers() []Handler {
+	prefix := o.Prefix
+	if prefix == "" {
+		prefix = "/debug"
+	}
+
+	handlers := []Handler{
+		{Path: prefix + "/pprof/", Handler: http.HandlerFunc(pprof.Index)},
+		{Path: prefix + "/pprof/cmdline", Handler: http.HandlerFunc(pprof.Cmdline)},
+		{Path: prefix + "/pprof/profile", Handler: http.HandlerFunc(pprof.Profile)},
+		{Path: prefix + "/pprof/symbol", Handler: http.HandlerFunc(pprof.Symbol), Methods: []string{http.MethodGet, http.MethodPost}},
+		{Path: prefix + "/pprof/trace", Handler: http.HandlerFunc(pprof.Trace)},
+		{Path: prefix + "/vars", Handler: expvar.Handler()},
+	}
+	// pprof.Index only serves named profiles under /debug/pprof/, so register each profile explicitly
+	for _, profile := range runtimePprof.Profiles() {
+		handlers = append(handlers, Handler{Path: prefix + "/pprof/" + profile.Name(), Handler: pprof.Handler(profile.Name())})
+	}
+	return handlers
+}
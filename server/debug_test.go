@@ -0,0 +1,82 @@
+package server_test
+
+import (
+	"errors"
+	"fmt"
+	"github.com/clambin/go-metrics/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestServer_Debug(t *testing.T) {
+	s := server.NewWithOptions(0, server.Options{Debug: &server.DebugOptions{}})
+	assert.Zero(t, s.DebugPort)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		err := s.Run()
+		require.True(t, errors.Is(err, http.ErrServerClosed))
+		wg.Done()
+	}()
+
+	body, err := httpGet(fmt.Sprintf("http://127.0.0.1:%d/debug/pprof/", s.Port))
+	require.NoError(t, err)
+	assert.Contains(t, body, "goroutine")
+
+	body, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/debug/pprof/goroutine?debug=1", s.Port))
+	require.NoError(t, err)
+	assert.Contains(t, body, "goroutine profile:")
+
+	body, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/debug/vars", s.Port))
+	require.NoError(t, err)
+	assert.Contains(t, body, `"memstats"`)
+
+	err = s.Shutdown(30 * time.Second)
+	require.NoError(t, err)
+	wg.Wait()
+}
+
+func TestServer_Debug_Separate(t *testing.T) {
+	s := server.NewWithOptions(0, server.Options{Debug: &server.DebugOptions{Prefix: "/admin", Separate: true}})
+	require.NotZero(t, s.DebugPort)
+	require.NotEqual(t, s.Port, s.DebugPort)
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		err := s.Run()
+		require.True(t, errors.Is(err, http.ErrServerClosed))
+		wg.Done()
+	}()
+
+	body, err := httpGet(fmt.Sprintf("http://127.0.0.1:%d/admin/pprof/heap?debug=1", s.DebugPort))
+	require.NoError(t, err)
+	assert.Contains(t, body, "heap profile:")
+
+	// requests to the separate debug listener aren't measured
+	body, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/metrics", s.Port))
+	require.NoError(t, err)
+	assert.NotContains(t, body, `path="/admin/pprof/heap"`)
+
+	_, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/admin/pprof/heap", s.Port))
+	assert.Error(t, err)
+
+	_, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/metrics", s.DebugPort))
+	assert.Error(t, err)
+
+	_, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/metrics", s.Port))
+	assert.NoError(t, err)
+
+	err = s.Shutdown(30 * time.Second)
+	require.NoError(t, err)
+	wg.Wait()
+}
+
+func TestServer_Debug_PortWithoutSeparate(t *testing.T) {
+	assert.Panics(t, func() { _ = server.NewWithOptions(0, server.Options{Debug: &server.DebugOptions{Port: 8081}}) })
+}
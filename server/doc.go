@@ -9,6 +9,14 @@ Server provides an HTTP server with a Prometheus metrics endpoint configured. Ru
 
 This will start an HTTP server on port 8080, with a /metrics endpoint for Prometheus scraping.
 
+Alternatively, Serve runs the server until the provided context is cancelled and then shuts it down gracefully.
+With HandleSignals set, the server also shuts down when it receives SIGINT or SIGTERM:
+
+	server := metrics.NewWithOptions(8080, metrics.Options{HandleSignals: true})
+	if err := server.Serve(context.Background()); err != nil {
+		panic(err)
+	}
+
 For HTTP servers, you may add additional handlers by using NewWithHandlers:
 
 	server := metrics.NewWithHandlers(8080, []metrics.Handler{
@@ -26,6 +34,28 @@ For HTTP servers, you may add additional handlers by using NewWithHandlers:
 	})
 	server.Run()
 
+Server also exposes /healthz, /readyz and /livez endpoints, which report the status of any registered health checks:
+
+	server.Health.Register(metrics.HealthCheck{
+		Name:  "database",
+		Check: func(ctx context.Context) error { return db.PingContext(ctx) },
+	})
+
+To add pprof and expvar endpoints, use NewWithOptions. Set Separate to serve them on their own port, rather than
+alongside the /metrics endpoint:
+
+	server := metrics.NewWithOptions(8080, metrics.Options{
+		Debug: &metrics.DebugOptions{Separate: true, Port: 6060},
+	})
+
+A Server can listen on several ports. E.g. to serve the metrics and health endpoints on an internal admin port,
+while the application's handlers listen on a public port:
+
+	server := metrics.NewWithOptions(8080, metrics.Options{
+		Handlers:  handlers,
+		Listeners: []metrics.Listener{{Name: "admin", Port: 9090, Metrics: true, Health: true}},
+	})
+
 If you need to build your own HTTP server, you can use GetRouter() instead:
 
 	r := metrics.GetRouter()
@@ -39,5 +69,21 @@ If you need to build your own HTTP server, you can use GetRouter() instead:
 	}
 	server.ListenAndServe()
 
+GetRouter relies on gorilla/mux to determine the path of each request. To measure requests handled by any other router,
+use NewMiddleware. E.g. for http.ServeMux:
+
+	m := http.NewServeMux()
+	m.HandleFunc("GET /users/{id}", userHandler)
+	_ = http.ListenAndServe(":8080", metrics.NewMiddleware(metrics.MiddlewareOptions{})(m))
+
+To log each request with log/slog, set the AccessLog option, or use NewAccessLogMiddleware with any router:
+
+	server := metrics.NewWithOptions(8080, metrics.Options{
+		Handlers:  handlers,
+		AccessLog: &metrics.AccessLogOptions{Logger: slog.Default(), Sample: 10},
+	})
+
+Sensitive headers and query parameters (e.g. Authorization, access_token) are redacted.
+
 */
 package server
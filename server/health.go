@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Health runs a set of registered HealthChecks and reports their status. Server creates a Health instance and exposes
+// its results on the /healthz, /readyz and /livez endpoints. To add a check:
+//
+//	s := server.New(8080)
+//	s.Health.Register(server.HealthCheck{
+//		Name:  "database",
+//		Check: func(ctx context.Context) error { return db.PingContext(ctx) },
+//	})
+//
+// Each check's status and duration are exported as the Prometheus metrics "health_check_status" and
+// "health_check_duration_seconds".
+type Health struct {
+	// CacheDuration determines how long the result of a check is reused before the check is performed again.
+	// If zero, each request performs the checks again.
+	CacheDuration time.Duration
+	checks        []HealthCheck
+	results       map[string]HealthCheckResult
+	lock          sync.RWMutex
+	notReady      atomic.Bool
+}
+
+// HealthCheck contains a single check to be performed by Health
+type HealthCheck struct {
+	// Name of the check. Must be unique
+	Name string
+	// Check performs the check. A non-nil error marks the check as failed
+	Check func(ctx context.Context) error
+	// Timeout of the check. If zero, DefaultHealthCheckTimeout is used
+	Timeout time.Duration
+	// Type determines which endpoints report the check. If zero, the check is both a readiness and a liveness check
+	Type HealthCheckType
+}
+
+// HealthCheckType determines whether a HealthCheck is a readiness check, a liveness check, or both
+type HealthCheckType int
+
+const (
+	// ReadinessCheck checks are reported by the /readyz endpoint
+	ReadinessCheck HealthCheckType = 1 << iota
+	// LivenessCheck checks are reported by the /livez endpoint
+	LivenessCheck
+)
+
+// DefaultHealthCheckTimeout is the default timeout for a HealthCheck
+const DefaultHealthCheckTimeout = 5 * time.Second
+
+// HealthCheckResult contains the outcome of a single HealthCheck
+type HealthCheckResult struct {
+	Name     string        `json:"name"`
+	Healthy  bool          `json:"healthy"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Time     time.Time     `json:"time"`
+}
+
+// HealthReport contains the outcome of a set of HealthChecks
+type HealthReport struct {
+	Healthy bool                `json:"healthy"`
+	Checks  []HealthCheckResult `json:"checks"`
+}
+
+// Prometheus metrics
+var (
+	healthCheckStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "health_check_status",
+		Help: "Status of a health check (1: healthy, 0: failed)",
+	}, []string{"check"})
+	healthCheckDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "health_check_duration_seconds",
+		Help: "Duration of the last run of a health check",
+	}, []string{"check"})
+)
+
+// Register adds one or more HealthChecks
+func (h *Health) Register(checks ...HealthCheck) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, check := range checks {
+		if check.Timeout == 0 {
+			check.Timeout = DefaultHealthCheckTimeout
+		}
+		if check.Type == 0 {
+			check.Type = ReadinessCheck | LivenessCheck
+		}
+		h.checks = append(h.checks, check)
+	}
+}
+
+// SetReady marks the application as ready or not ready. When not ready, all readiness reports are unhealthy,
+// regardless of the outcome of the readiness checks. Server.Serve uses this to stop receiving new requests
+// before shutting down.
+func (h *Health) SetReady(ready bool) {
+	h.notReady.Store(!ready)
+}
+
+// Check performs all checks of the specified type and returns the outcome. Checks are performed concurrently.
+// A check whose last result is younger than CacheDuration is not performed again. If checkType is zero,
+// all checks are performed.
+func (h *Health) Check(ctx context.Context, checkType HealthCheckType) HealthReport {
+	h.lock.RLock()
+	var checks []HealthCheck
+	for _, check := range h.checks {
+		if checkType == 0 || check.Type&checkType != 0 {
+			checks = append(checks, check)
+		}
+	}
+	h.lock.RUnlock()
+
+	report := HealthReport{Healthy: true, Checks: make([]HealthCheckResult, len(checks))}
+	var wg sync.WaitGroup
+	for index := range checks {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			report.Checks[index] = h.check(ctx, checks[index])
+		}(index)
+	}
+	wg.Wait()
+
+	if checkType != LivenessCheck && h.notReady.Load() {
+		report.Checks = append(report.Checks, HealthCheckResult{Name: "ready", Error: "not ready", Time: time.Now()})
+	}
+
+	for _, result := range report.Checks {
+		report.Healthy = report.Healthy && result.Healthy
+	}
+	sort.Slice(report.Checks, func(i, j int) bool { return report.Checks[i].Name < report.Checks[j].Name })
+	return report
+}
+
+// check returns the result of the check. The check runs on a context detached from ctx, so a request that is
+// cancelled doesn't fail the check. If ctx is cancelled before the check completes, check returns a failed result,
+// which is neither cached nor reported in the metrics. The check's own result is still cached once it completes.
+func (h *Health) check(ctx context.Context, check HealthCheck) HealthCheckResult {
+	h.lock.RLock()
+	result, found := h.results[check.Name]
+	h.lock.RUnlock()
+	if found && time.Since(result.Time) < h.CacheDuration {
+		return result
+	}
+
+	results := make(chan HealthCheckResult, 1)
+	go func() { results <- h.run(context.WithoutCancel(ctx), check) }()
+	select {
+	case result = <-results:
+		return result
+	case <-ctx.Done():
+		return HealthCheckResult{Name: check.Name, Error: "cancelled: " + ctx.Err().Error(), Time: time.Now()}
+	}
+}
+
+// run performs the check, records its result and reports it in the metrics
+func (h *Health) run(ctx context.Context, check HealthCheck) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, check.Timeout)
+	defer cancel()
+
+	result := HealthCheckResult{Name: check.Name, Time: time.Now()}
+	err := runCheck(ctx, check.Check)
+	result.Duration = time.Since(result.Time)
+	result.Healthy = err == nil
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	h.lock.Lock()
+	if h.results == nil {
+		h.results = make(map[string]HealthCheckResult)
+	}
+	h.results[check.Name] = result
+	h.lock.Unlock()
+
+	var status float64
+	if result.Healthy {
+		status = 1
+	}
+	healthCheckStatus.WithLabelValues(check.Name).Set(status)
+	healthCheckDuration.WithLabelValues(check.Name).Set(result.Duration.Seconds())
+	return result
+}
+
+// runCheck performs the check, returning when the check returns or when the context times out, whichever comes first.
+func runCheck(ctx context.Context, check func(ctx context.Context) error) error {
+	ch := make(chan error, 1)
+	go func() { ch <- check(ctx) }()
+	select {
+	case err := <-ch:
+		return err
+	case <-ctx.Done():
+		return errors.New("timeout: " + ctx.Err().Error())
+	}
+}
+
+// Handlers returns the /healthz, /readyz and /livez endpoints. Server adds these automatically, unless the Server's
+// own handlers already serve these paths. Use Handlers to add them to your own router. Each endpoint returns
+// a HealthReport in JSON format, with status code 200 if all checks are healthy, or 503 otherwise.
+func (h *Health) Handlers() []Handler {
+	return []Handler{
+		{Path: "/healthz", Handler: h.handler(0)},
+		{Path: "/readyz", Handler: h.handler(ReadinessCheck)},
+		{Path: "/livez", Handler: h.handler(LivenessCheck)},
+	}
+}
+
+func (h *Health) handler(checkType HealthCheckType) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := h.Check(r.Context(), checkType)
+		w.Header().Set("Content-Type", "application/json")
+		if !report.Healthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
@@ -0,0 +1,173 @@
+package server_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/clambin/go-metrics/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHealth_Check(t *testing.T) {
+	h := server.Health{}
+	h.Register(
+		server.HealthCheck{Name: "ok", Check: func(_ context.Context) error { return nil }},
+		server.HealthCheck{Name: "failing", Check: func(_ context.Context) error { return errors.New("failed") }, Type: server.ReadinessCheck},
+		server.HealthCheck{Name: "slow", Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}, Timeout: 10 * time.Millisecond, Type: server.LivenessCheck},
+	)
+
+	report := h.Check(context.Background(), 0)
+	assert.False(t, report.Healthy)
+	require.Len(t, report.Checks, 3)
+	assert.Equal(t, "failing", report.Checks[0].Name)
+	assert.Equal(t, "failed", report.Checks[0].Error)
+	assert.Equal(t, "ok", report.Checks[1].Name)
+	assert.True(t, report.Checks[1].Healthy)
+	assert.Equal(t, "slow", report.Checks[2].Name)
+	assert.Equal(t, "timeout: context deadline exceeded", report.Checks[2].Error)
+
+	report = h.Check(context.Background(), server.ReadinessCheck)
+	assert.False(t, report.Healthy)
+	assert.Len(t, report.Checks, 2)
+
+	report = h.Check(context.Background(), server.LivenessCheck)
+	assert.False(t, report.Healthy)
+	assert.Len(t, report.Checks, 2)
+
+	body := scrapeMetrics(t)
+	assert.Contains(t, body, `
+health_check_status{check="failing"} 0
+`)
+	assert.Contains(t, body, `
+health_check_status{check="ok"} 1
+`)
+	assert.Contains(t, body, `
+health_check_duration_seconds{check="slow"} `)
+}
+
+func TestHealth_Check_Cached(t *testing.T) {
+	var calls atomic.Int32
+	h := server.Health{CacheDuration: time.Hour}
+	h.Register(server.HealthCheck{Name: "cached", Check: func(_ context.Context) error {
+		calls.Add(1)
+		return nil
+	}})
+
+	assert.True(t, h.Check(context.Background(), 0).Healthy)
+	assert.True(t, h.Check(context.Background(), server.ReadinessCheck).Healthy)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestHealth_Check_Cancelled(t *testing.T) {
+	h := server.Health{CacheDuration: time.Hour}
+	release := make(chan struct{})
+	h.Register(server.HealthCheck{Name: "slow", Check: func(ctx context.Context) error {
+		select {
+		case <-release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}})
+
+	// the caller gives up: the check keeps running and its failure isn't cached
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	report := h.Check(ctx, 0)
+	assert.False(t, report.Healthy)
+	require.Len(t, report.Checks, 1)
+	assert.Equal(t, "cancelled: context canceled", report.Checks[0].Error)
+
+	close(release)
+	assert.Eventually(t, func() bool { return h.Check(context.Background(), 0).Healthy }, time.Second, 10*time.Millisecond)
+}
+
+func TestHealth_Handlers(t *testing.T) {
+	var healthy atomic.Bool
+	h := server.Health{}
+	h.Register(server.HealthCheck{Name: "toggle", Check: func(_ context.Context) error {
+		if !healthy.Load() {
+			return errors.New("not healthy")
+		}
+		return nil
+	}})
+
+	handlers := h.Handlers()
+	require.Len(t, handlers, 3)
+
+	for _, handler := range handlers {
+		w := httptest.NewRecorder()
+		handler.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, handler.Path, nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, handler.Path)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var report server.HealthReport
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+		assert.False(t, report.Healthy)
+		require.Len(t, report.Checks, 1)
+		assert.Equal(t, "not healthy", report.Checks[0].Error)
+	}
+
+	healthy.Store(true)
+	for _, handler := range handlers {
+		w := httptest.NewRecorder()
+		handler.Handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, handler.Path, nil))
+		assert.Equal(t, http.StatusOK, w.Code, handler.Path)
+	}
+}
+
+func TestServer_Health(t *testing.T) {
+	s := server.New(0)
+	s.Health.Register(server.HealthCheck{Name: "server", Check: func(_ context.Context) error { return nil }})
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		err := s.Run()
+		require.True(t, errors.Is(err, http.ErrServerClosed))
+		wg.Done()
+	}()
+
+	for _, path := range []string{"/healthz", "/readyz", "/livez"} {
+		body, err := httpGet(fmt.Sprintf("http://127.0.0.1:%d%s", s.Port, path))
+		require.NoError(t, err, path)
+		assert.Contains(t, body, `"healthy":true`, path)
+		assert.Contains(t, body, `"name":"server"`, path)
+	}
+
+	err := s.Shutdown(30 * time.Second)
+	require.NoError(t, err)
+	wg.Wait()
+}
+
+func TestServer_Health_Override(t *testing.T) {
+	s := server.NewWithHandlers(0, []server.Handler{{
+		Path:    "/healthz",
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("custom")) }),
+	}})
+	go func() { _ = s.Run() }()
+	defer func() { _ = s.Shutdown(time.Second) }()
+
+	// the application's own /healthz handler takes precedence
+	body, err := httpGet(fmt.Sprintf("http://127.0.0.1:%d/healthz", s.Port))
+	require.NoError(t, err)
+	assert.Equal(t, "custom", body)
+
+	body, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/readyz", s.Port))
+	require.NoError(t, err)
+	assert.Contains(t, body, `"healthy":true`)
+}
@@ -0,0 +1,28 @@
+package server
+
+// Listener is an additional HTTP listener of a Server, with its own port and handlers. All listeners are started and
+// shut down together. By default, the Server's main listener (i.e. the one listening on the Server's Port) serves
+// the metrics, health and debug endpoints. Setting Metrics, Health or Debug moves those endpoints to the Listener.
+//
+// E.g. to serve the business handlers on a public port and the metrics & health endpoints on an internal admin port:
+//
+//	s := server.NewWithOptions(8080, server.Options{
+//		Handlers:  handlers,
+//		Listeners: []server.Listener{{Name: "admin", Port: 9090, Metrics: true, Health: true}},
+//	})
+type Listener struct {
+	// Name of the listener. Must be unique. The listener's port can be found in Server's Ports field, under this name.
+	// The name "debug" is reserved for the listener created by DebugOptions.Separate
+	Name string
+	// Port that the listener listens on. If zero, a randomly chosen free port is used
+	Port int
+	// Handlers contains the handlers served by the listener
+	Handlers []Handler
+	// Metrics serves the /metrics endpoint on this listener
+	Metrics bool
+	// Health serves the /healthz, /readyz and /livez endpoints on this listener
+	Health bool
+	// Debug serves the pprof and expvar endpoints on this listener, as configured by Options.Debug. Only one listener
+	// can serve them, including the one created by DebugOptions.Separate
+	Debug bool
+}
@@ -0,0 +1,102 @@
+package server_test
+
+import (
+	"context"
+	"fmt"
+	"github.com/clambin/go-metrics/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestServer_Listeners(t *testing.T) {
+	s := server.NewWithOptions(0, server.Options{
+		Handlers: []server.Handler{{
+			Path: "/public",
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("public"))
+			}),
+		}},
+		Debug: &server.DebugOptions{},
+		Listeners: []server.Listener{
+			{
+				Name:    "admin",
+				Metrics: true,
+				Health:  true,
+				Debug:   true,
+				Handlers: []server.Handler{{
+					Path: "/admin",
+					Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+						_, _ = w.Write([]byte("admin"))
+					}),
+				}},
+			},
+			{Name: "empty"},
+		},
+	})
+	require.Len(t, s.Ports, 2)
+	admin := s.Ports["admin"]
+	require.NotZero(t, admin)
+	assert.Equal(t, admin, s.DebugPort)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() { errCh <- s.Serve(ctx) }()
+
+	for _, tc := range []struct {
+		port int
+		path string
+		ok   bool
+	}{
+		{port: s.Port, path: "/public", ok: true},
+		{port: s.Port, path: "/admin", ok: false},
+		{port: s.Port, path: "/metrics", ok: false},
+		{port: s.Port, path: "/healthz", ok: false},
+		{port: s.Port, path: "/debug/vars", ok: false},
+		{port: admin, path: "/public", ok: false},
+		{port: admin, path: "/admin", ok: true},
+		{port: admin, path: "/metrics", ok: true},
+		{port: admin, path: "/healthz", ok: true},
+		{port: admin, path: "/debug/vars", ok: true},
+		{port: s.Ports["empty"], path: "/metrics", ok: false},
+	} {
+		_, err := httpGet(fmt.Sprintf("http://127.0.0.1:%d%s", tc.port, tc.path))
+		assert.Equal(t, tc.ok, err == nil, "%d%s", tc.port, tc.path)
+	}
+
+	body, err := httpGet(fmt.Sprintf("http://127.0.0.1:%d/metrics", admin))
+	require.NoError(t, err)
+	assert.Contains(t, body, `
+http_duration_seconds_count{method="GET",path="/public",status_code="200"} `)
+	assert.Contains(t, body, `
+http_duration_seconds_count{method="GET",path="/admin",status_code="200"} `)
+	// the debug endpoints aren't measured, whichever listener serves them
+	assert.NotContains(t, body, `path="/debug/vars"`)
+
+	cancel()
+	assert.NoError(t, <-errCh)
+}
+
+func TestServer_Listeners_Duplicate(t *testing.T) {
+	assert.Panics(t, func() {
+		_ = server.NewWithOptions(0, server.Options{Listeners: []server.Listener{{Name: "foo"}, {Name: "foo"}}})
+	})
+}
+
+func TestServer_Listeners_Debug(t *testing.T) {
+	// "debug" is reserved for the separate debug listener
+	assert.Panics(t, func() {
+		_ = server.NewWithOptions(0, server.Options{Listeners: []server.Listener{{Name: "debug"}}})
+	})
+	// only one listener can serve the debug endpoints
+	assert.Panics(t, func() {
+		_ = server.NewWithOptions(0, server.Options{Listeners: []server.Listener{{Name: "foo", Debug: true}, {Name: "bar", Debug: true}}})
+	})
+	assert.Panics(t, func() {
+		_ = server.NewWithOptions(0, server.Options{
+			Debug:     &server.DebugOptions{Separate: true},
+			Listeners: []server.Listener{{Name: "foo", Debug: true}},
+		})
+	})
+}
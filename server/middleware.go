@@ -0,0 +1,99 @@
+package server
+
+import (
+	"github.com/clambin/go-metrics/internal/sanitize"
+	"github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RouteNameFunc returns the name of the route that handled the request, typically its path template (e.g. "/users/{id}").
+// If it returns an empty string, the middleware falls back to the request's normalised path.
+type RouteNameFunc func(r *http.Request) string
+
+// MiddlewareOptions contains options to alter the behaviour of the middleware created by NewMiddleware
+type MiddlewareOptions struct {
+	// RouteName determines the path label of a request. If nil, the middleware uses the request's Pattern,
+	// as set by http.ServeMux.
+	RouteName RouteNameFunc
+	// MaxPaths limits the number of distinct normalised paths that are recorded when no route name is found.
+	// Once reached, any new path is recorded as "other". If zero, DefaultMaxPaths is used.
+	MaxPaths int
+	// Metric records the duration of each request. It must have the labels path, method and status_code.
+	// If nil, the "http_duration_seconds" metric is used
+	Metric *prometheus.SummaryVec
+}
+
+// DefaultMaxPaths is the default maximum number of distinct normalised paths recorded by NewMiddleware
+const DefaultMaxPaths = 100
+
+// NewMiddleware returns an HTTP middleware that records the duration of each request in the "http_duration_seconds" metric,
+// or in the metric set in MiddlewareOptions.
+// Contrary to GetRouter, it does not depend on gorilla/mux and can be used with any router:
+//
+//	m := http.NewServeMux()
+//	m.HandleFunc("GET /users/{id}", userHandler)
+//	_ = http.ListenAndServe(":8080", server.NewMiddleware(server.MiddlewareOptions{})(m))
+//
+// The path label is determined by the RouteName function in MiddlewareOptions. If no RouteName function is provided,
+// the request's Pattern (as set by http.ServeMux) is used. If no route name can be determined, the middleware
+// uses the request's path, with any numerical, UUID or hexadecimal segments replaced by ":id". To protect against
+// high cardinality, the number of such paths is limited by MaxPaths.
+func NewMiddleware(options MiddlewareOptions) func(next http.Handler) http.Handler {
+	if options.RouteName == nil {
+		options.RouteName = patternRouteName
+	}
+	if options.MaxPaths == 0 {
+		options.MaxPaths = DefaultMaxPaths
+	}
+	if options.Metric == nil {
+		options.Metric = httpDuration
+	}
+	paths := pathLimiter{maxPaths: options.MaxPaths, paths: make(map[string]struct{})}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lrw := newLoggingResponseWriter(w)
+			start := time.Now()
+			next.ServeHTTP(lrw, r)
+			// http.ServeMux only sets the request's Pattern once it has routed the request, so determine the path afterwards
+			path := options.RouteName(r)
+			if path == "" {
+				path = paths.get(sanitize.Path(r.URL.Path))
+			}
+			options.Metric.WithLabelValues(path, r.Method, strconv.Itoa(lrw.statusCode)).Observe(time.Since(start).Seconds())
+		})
+	}
+}
+
+// patternRouteName returns the request's Pattern, without the method. E.g. "GET /users/{id}" becomes "/users/{id}".
+func patternRouteName(r *http.Request) string {
+	pattern := r.Pattern
+	if index := strings.IndexByte(pattern, ' '); index != -1 {
+		pattern = strings.TrimLeft(pattern[index:], " \t")
+	}
+	return pattern
+}
+
+// pathLimiter limits the number of distinct paths to maxPaths. Any new paths are reported as "other".
+type pathLimiter struct {
+	maxPaths int
+	paths    map[string]struct{}
+	lock     sync.Mutex
+}
+
+func (p *pathLimiter) get(path string) string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if _, ok := p.paths[path]; ok {
+		return path
+	}
+	if len(p.paths) >= p.maxPaths {
+		return "other"
+	}
+	p.paths[path] = struct{}{}
+	return path
+}
@@ -0,0 +1,90 @@
+package server_test
+
+import (
+	"github.com/clambin/go-metrics/server"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewMiddleware_ServeMux(t *testing.T) {
+	m := http.NewServeMux()
+	m.HandleFunc("GET /servemux/{id}", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("hello!"))
+	})
+	metric := newDurationMetric()
+	h := server.NewMiddleware(server.MiddlewareOptions{Metric: metric})(m)
+
+	for _, path := range []string{"/servemux/1", "/servemux/2", "/notfound/3"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	assertRequestCount(t, metric, prometheus.Labels{"method": http.MethodGet, "path": "/servemux/{id}", "status_code": "200"}, 2)
+	assertRequestCount(t, metric, prometheus.Labels{"method": http.MethodGet, "path": "/notfound/:id", "status_code": "404"}, 1)
+}
+
+func TestNewMiddleware_DefaultMetric(t *testing.T) {
+	h := server.NewMiddleware(server.MiddlewareOptions{})(http.HandlerFunc(func(_ http.ResponseWriter, _ *http.Request) {}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/defaultmetric", nil))
+
+	assert.Contains(t, scrapeMetrics(t), `
+http_duration_seconds_count{method="GET",path="/defaultmetric",status_code="200"} `)
+}
+
+func TestNewMiddleware_RouteName(t *testing.T) {
+	metric := newDurationMetric()
+	h := server.NewMiddleware(server.MiddlewareOptions{
+		Metric: metric,
+		RouteName: func(r *http.Request) string {
+			if r.URL.Path == "/routename/foo" {
+				return "foo"
+			}
+			return ""
+		},
+		MaxPaths: 1,
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	for _, path := range []string{
+		"/routename/foo",
+		"/routename/bar/550e8400-e29b-41d4-a716-446655440000",
+		"/routename/bar/123",
+		"/routename/snafu",
+	} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+
+	assertRequestCount(t, metric, prometheus.Labels{"method": http.MethodPost, "path": "foo", "status_code": "201"}, 1)
+	assertRequestCount(t, metric, prometheus.Labels{"method": http.MethodPost, "path": "/routename/bar/:id", "status_code": "201"}, 2)
+	assertRequestCount(t, metric, prometheus.Labels{"method": http.MethodPost, "path": "other", "status_code": "201"}, 1)
+}
+
+func newDurationMetric() *prometheus.SummaryVec {
+	return prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Name: "http_duration_seconds",
+		Help: "Duration of HTTP requests",
+	}, []string{"path", "method", "status_code"})
+}
+
+func assertRequestCount(t *testing.T, metric *prometheus.SummaryVec, labels prometheus.Labels, expected uint64) {
+	t.Helper()
+	m, err := tools.Collect(metric)
+	require.NoError(t, err)
+	summary, err := m.Summary("http_duration_seconds", labels)
+	require.NoError(t, err)
+	assert.Equal(t, expected, summary.GetSampleCount())
+}
+
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	promhttp.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	return w.Body.String()
+}
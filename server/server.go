@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
@@ -9,7 +10,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 )
 
@@ -17,7 +21,19 @@ import (
 // Counter metric that measures the time of each HTTP server request.
 type Server struct {
 	// the Port that the HTTP server listens on
-	Port     int
+	Port int
+	// the Port that the debug endpoints listen on, if they are served by a separate listener
+	DebugPort int
+	// the Ports of any additional listeners, by listener name
+	Ports map[string]int
+	// Health reports the status of the application on the /healthz, /readyz and /livez endpoints
+	Health  *Health
+	servers []*httpServer
+	options Options
+}
+
+// httpServer is a single HTTP server managed by Server
+type httpServer struct {
 	listener net.Listener
 	server   http.Server
 }
@@ -41,8 +57,112 @@ type Handler struct {
 // NewWithHandlers creates a new Server with additional handlers. If Port is zero, Server will listen on
 // a randomly chosen free port.  The selected can be found in Server's Port field.
 func NewWithHandlers(port int, handlers []Handler) *Server {
+	return NewWithOptions(port, Options{Handlers: handlers})
+}
+
+// Options contains options to alter Server behaviour
+type Options struct {
+	// Handlers contains additional handlers to add to the Server's HTTP server
+	Handlers []Handler
+	// Debug adds pprof and expvar endpoints to the Server. If nil, no debug endpoints are added
+	Debug *DebugOptions
+	// Listeners contains any additional listeners the Server should start, each with their own handlers
+	Listeners []Listener
+	// ShutdownTimeout is the maximum time Serve waits for in-flight requests to complete when shutting down.
+	// If zero, DefaultShutdownTimeout is used
+	ShutdownTimeout time.Duration
+	// ShutdownDelay is the time Serve waits between marking the Server as not ready and shutting down the HTTP servers.
+	// This gives load balancers time to stop sending new requests to the Server
+	ShutdownDelay time.Duration
+	// HandleSignals shuts down the Server when Serve receives a SIGINT or SIGTERM signal
+	HandleSignals bool
+	// AccessLog logs each request served by the Server's listeners. If nil, requests are not logged
+	AccessLog *AccessLogOptions
+}
+
+// DefaultShutdownTimeout is the default time Serve waits for in-flight requests to complete
+const DefaultShutdownTimeout = 30 * time.Second
+
+// NewWithOptions creates a new Server with the provided Options. If Port is zero, Server will listen on
+// a randomly chosen free port.  The selected can be found in Server's Port field.
+func NewWithOptions(port int, options Options) *Server {
+	if options.Debug != nil && options.Debug.Port != 0 && !options.Debug.Separate {
+		panic("debug port requires a separate debug listener")
+	}
+	listeners := append([]Listener{}, options.Listeners...)
+	if options.Debug != nil && options.Debug.Separate {
+		listeners = append(listeners, Listener{Name: debugListenerName, Port: options.Debug.Port, Debug: true})
+	}
+	var debugListeners int
+	for index, listener := range listeners {
+		if listener.Name == debugListenerName && index < len(options.Listeners) {
+			panic("listener name is reserved: " + debugListenerName)
+		}
+		if listener.Debug {
+			debugListeners++
+		}
+	}
+	if debugListeners > 1 {
+		panic("only one listener can serve the debug endpoints")
+	}
+
+	s := &Server{Health: &Health{}, Ports: make(map[string]int), options: options}
+
+	// the main listener serves the metrics, health & debug endpoints, unless another listener serves them
+	main := Listener{Port: port, Handlers: options.Handlers, Metrics: true, Health: true, Debug: options.Debug != nil}
+	for _, listener := range listeners {
+		main.Metrics = main.Metrics && !listener.Metrics
+		main.Health = main.Health && !listener.Health
+		main.Debug = main.Debug && !listener.Debug
+	}
+	s.Port = s.addListener(main)
+
+	for _, listener := range listeners {
+		if _, found := s.Ports[listener.Name]; found {
+			s.closeListeners()
+			panic("duplicate listener name: " + listener.Name)
+		}
+		s.Ports[listener.Name] = s.addListener(listener)
+		if listener.Debug {
+			s.DebugPort = s.Ports[listener.Name]
+		}
+	}
+
+	return s
+}
+
+// debugListenerName is the name of the listener that serves the debug endpoints if DebugOptions.Separate is set
+const debugListenerName = "debug"
+
+func (server *Server) addListener(listener Listener) int {
+	var handlers []Handler
+	if listener.Health {
+		handlers = append(handlers, server.Health.Handlers()...)
+	}
+	if listener.Debug {
+		debug := server.options.Debug
+		if debug == nil {
+			debug = &DebugOptions{}
+		}
+		for _, handler := range debug.handlers() {
+			handler.Handler = unmeasuredHandler{Handler: handler.Handler}
+			handlers = append(handlers, handler)
+		}
+	}
+
+	r := newRouter(listener.Metrics)
+	if server.options.AccessLog != nil {
+		r.Use(NewAccessLogMiddleware(*server.options.AccessLog))
+	}
+	// register the listener's own handlers first, so they take precedence over the built-in ones
+	addHandlers(r, append(append([]Handler{}, listener.Handlers...), handlers...))
+	return server.addServer(listener.Port, r)
+}
+
+func (server *Server) addServer(port int, handler http.Handler) int {
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
 	if err != nil {
+		server.closeListeners()
 		panic("unable to create prometheus metrics server")
 	}
 
@@ -50,7 +170,20 @@ func NewWithHandlers(port int, handlers []Handler) *Server {
 		port = listener.Addr().(*net.TCPAddr).Port
 	}
 
-	r := GetRouter()
+	server.servers = append(server.servers, &httpServer{
+		listener: listener,
+		server:   http.Server{Handler: handler},
+	})
+	return port
+}
+
+func (server *Server) closeListeners() {
+	for _, s := range server.servers {
+		_ = s.listener.Close()
+	}
+}
+
+func addHandlers(r *mux.Router, handlers []Handler) {
 	for _, handler := range handlers {
 		methods := handler.Methods
 		if handler.Methods == nil || len(handler.Methods) == 0 {
@@ -58,12 +191,6 @@ func NewWithHandlers(port int, handlers []Handler) *Server {
 		}
 		r.Path(handler.Path).Handler(handler.Handler).Methods(methods...)
 	}
-
-	return &Server{
-		Port:     port,
-		listener: listener,
-		server:   http.Server{Handler: r},
-	}
 }
 
 // GetRouter returns an HTTP router with a prometheus metrics endpoint. Use this if you do not want to use Server.Run(),
@@ -75,22 +202,108 @@ func NewWithHandlers(port int, handlers []Handler) *Server {
 //			Addr: ":8080",
 //
 func GetRouter() (router *mux.Router) {
+	return newRouter(true)
+}
+
+// newRouter returns an HTTP router that measures the duration of each request. If metrics is set, the router
+// also serves the prometheus metrics endpoint.
+func newRouter(metrics bool) (router *mux.Router) {
 	router = mux.NewRouter()
 	router.Use(prometheusMiddleware)
-	router.Path("/metrics").Handler(promhttp.Handler())
+	if metrics {
+		router.Path("/metrics").Handler(promhttp.Handler())
+	}
 	return
 }
 
-// Run starts the HTTP Server. This calls server's http.Server's Serve method and returns that method's return value.
+// Run starts the HTTP Server. This calls each http.Server's Serve method and returns the first error returned by those
+// methods. If one of the HTTP servers fails, Run shuts down the others. Run blocks until all HTTP servers have stopped.
 func (server *Server) Run() (err error) {
-	return server.server.Serve(server.listener)
+	errs := server.start()
+	if err = <-errs; !errors.Is(err, http.ErrServerClosed) {
+		// one of the HTTP servers failed. shut down the others
+		_ = server.Shutdown(server.shutdownTimeout())
+	}
+	for pending := len(server.servers) - 1; pending > 0; pending-- {
+		<-errs
+	}
+	return
+}
+
+// Serve runs the HTTP Server until the provided context is cancelled, or, if Options.HandleSignals is set, until
+// the Server receives a SIGINT or SIGTERM signal. It then marks the Server as not ready, waits for Options.ShutdownDelay
+// and shuts down the HTTP servers, allowing in-flight requests up to Options.ShutdownTimeout to complete.
+//
+// Contrary to Run, Serve returns nil when the Server shuts down cleanly:
+//
+//	s := server.New(8080)
+//	if err := s.Serve(ctx); err != nil {
+//		panic(err)
+//	}
+func (server *Server) Serve(ctx context.Context) (err error) {
+	if server.options.HandleSignals {
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+		defer stop()
+	}
+
+	errs := server.start()
+	pending := len(server.servers)
+
+	select {
+	case <-ctx.Done():
+		server.Health.SetReady(false)
+		time.Sleep(server.options.ShutdownDelay)
+	case err = <-errs:
+		// one of the HTTP servers failed. shut down the others
+		pending--
+	}
+
+	if err2 := server.Shutdown(server.shutdownTimeout()); err == nil {
+		err = err2
+	}
+
+	for ; pending > 0; pending-- {
+		if err2 := <-errs; err == nil {
+			err = err2
+		}
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+	return
+}
+
+func (server *Server) shutdownTimeout() time.Duration {
+	if server.options.ShutdownTimeout == 0 {
+		return DefaultShutdownTimeout
+	}
+	return server.options.ShutdownTimeout
+}
+
+// start runs each HTTP server in a separate goroutine. The returned channel receives the return value of each
+// server's Serve method.
+func (server *Server) start() chan error {
+	errs := make(chan error, len(server.servers))
+	for _, s := range server.servers {
+		go func(s *httpServer) {
+			errs <- s.server.Serve(s.listener)
+		}(s)
+	}
+	return errs
 }
 
 // Shutdown performs a graceful shutdown of the HTTP Server.
 func (server *Server) Shutdown(timeout time.Duration) (err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
-	return server.server.Shutdown(ctx)
+	for _, s := range server.servers {
+		if err2 := s.server.Shutdown(ctx); err == nil {
+			err = err2
+		}
+	}
+	return
 }
 
 // Prometheus metrics
@@ -101,10 +314,19 @@ var (
 	}, []string{"path", "method", "status_code"})
 )
 
+// unmeasuredHandler marks a route whose requests prometheusMiddleware doesn't measure (e.g. the debug endpoints)
+type unmeasuredHandler struct {
+	http.Handler
+}
+
 // prometheusMiddleware measures the time it takes to perform a /metric call.
 func prometheusMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		route := mux.CurrentRoute(r)
+		if _, ok := route.GetHandler().(unmeasuredHandler); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
 		path, _ := route.GetPathTemplate()
 		lrw := newLoggingResponseWriter(w)
 		start := time.Now()
@@ -118,6 +340,7 @@ func prometheusMiddleware(next http.Handler) http.Handler {
 type loggingResponseWriter struct {
 	http.ResponseWriter
 	statusCode int
+	bytes      int
 }
 
 // newLoggingResponseWriter creates a new loggingResponseWriter.
@@ -136,5 +359,7 @@ func (w *loggingResponseWriter) WriteHeader(code int) {
 
 // Write implements the http.ResponseWriter interface.
 func (w *loggingResponseWriter) Write(body []byte) (int, error) {
-	return w.ResponseWriter.Write(body)
+	n, err := w.ResponseWriter.Write(body)
+	w.bytes += n
+	return n, err
 }
@@ -0,0 +1,25 @@
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestServer_Run_Failure(t *testing.T) {
+	s := NewWithOptions(0, Options{Listeners: []Listener{{Name: "admin", Health: true}}})
+	// closing its listener makes the admin server fail as soon as it starts
+	_ = s.servers[1].listener.Close()
+
+	errCh := make(chan error)
+	go func() { errCh <- s.Run() }()
+
+	select {
+	case err := <-errCh:
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, http.ErrServerClosed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return")
+	}
+}
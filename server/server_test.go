@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"github.com/clambin/go-metrics/server"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"io"
 	"net"
 	"net/http"
+	"os"
 	"sync"
 	"testing"
 	"time"
@@ -108,12 +111,77 @@ func TestServer_Metrics(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "hello2!", body)
 
-	body, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/metrics", s.Port))
+	families, err := tools.Scrape(fmt.Sprintf("http://127.0.0.1:%d/metrics", s.Port))
 	require.NoError(t, err)
-	assert.Contains(t, body, `
-http_duration_seconds_count{method="GET",path="/hello",status_code="200"} `)
-	assert.Contains(t, body, `
-http_duration_seconds_count{method="POST",path="/hello2",status_code="201"} `)
+	// http_duration_seconds is registered globally, so other tests (or runs) may have added to the count
+	count, err := families.Value("http_duration_seconds_count", prometheus.Labels{"method": http.MethodGet, "path": "/hello", "status_code": "200"})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, count, 1.0)
+	count, err = families.Value("http_duration_seconds_count", prometheus.Labels{"method": http.MethodPost, "path": "/hello2", "status_code": "201"})
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, count, 1.0)
+}
+
+func TestServer_Serve(t *testing.T) {
+	s := server.NewWithOptions(0, server.Options{
+		Handlers: []server.Handler{{
+			Path: "/slow",
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(200 * time.Millisecond)
+				_, _ = w.Write([]byte("done"))
+			}),
+		}},
+		ShutdownDelay: 200 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() { errCh <- s.Serve(ctx) }()
+
+	_, err := httpGet(fmt.Sprintf("http://127.0.0.1:%d/readyz", s.Port))
+	require.NoError(t, err)
+
+	slowCh := make(chan string)
+	go func() {
+		body, _ := httpGet(fmt.Sprintf("http://127.0.0.1:%d/slow", s.Port))
+		slowCh <- body
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	// during ShutdownDelay, the server still responds, but is no longer ready
+	assert.Eventually(t, func() bool {
+		_, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/readyz", s.Port))
+		return err != nil && err.Error() == "503 Service Unavailable"
+	}, time.Second, 10*time.Millisecond)
+	_, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/livez", s.Port))
+	assert.NoError(t, err)
+
+	// in-flight requests are completed
+	assert.Equal(t, "done", <-slowCh)
+	assert.NoError(t, <-errCh)
+
+	_, err = httpGet(fmt.Sprintf("http://127.0.0.1:%d/readyz", s.Port))
+	assert.Error(t, err)
+}
+
+func TestServer_Serve_Signal(t *testing.T) {
+	s := server.NewWithOptions(0, server.Options{HandleSignals: true})
+
+	errCh := make(chan error)
+	go func() { errCh <- s.Serve(context.Background()) }()
+
+	require.Eventually(t, func() bool {
+		_, err := httpGet(fmt.Sprintf("http://127.0.0.1:%d/healthz", s.Port))
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	p, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, p.Signal(os.Interrupt))
+
+	assert.NoError(t, <-errCh)
 }
 
 func TestServer_Panics(t *testing.T) {
@@ -147,10 +215,11 @@ func TestGetRouter(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, "hello!", body)
 
-	body, err = httpGet(target + "/metrics")
+	families, err := tools.Scrape(target + "/metrics")
 	require.NoError(t, err)
-	assert.Contains(t, body, `
-http_duration_seconds_sum{method="GET",path="/hello",status_code="200"} `)
+	sum, err := families.Value("http_duration_seconds_sum", prometheus.Labels{"method": http.MethodGet, "path": "/hello", "status_code": "200"})
+	require.NoError(t, err)
+	assert.NotZero(t, sum)
 
 	err = s.Shutdown(context.Background())
 	require.NoError(t, err)
@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	pcg "github.com/prometheus/client_model/go"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metrics contains the metrics collected by Collect, keyed by MetricKey
+type Metrics map[string]*pcg.Metric
+
+// DefaultCollectTimeout is the time Collect waits for a Collector to finish collecting its metrics
+const DefaultCollectTimeout = 5 * time.Second
+
+// Collect collects all metrics from the provided Collector:
+//
+//	metrics, err := tools.Collect(c)
+//	require.NoError(t, err)
+//	value, err := metrics.Counter("foo_errors_total", prometheus.Labels{"endpoint": "/foo"})
+//	require.NoError(t, err)
+//	assert.Equal(t, 1.0, value)
+//
+// If the Collector doesn't finish collecting within DefaultCollectTimeout, Collect returns an error.
+func Collect(collector prometheus.Collector) (Metrics, error) {
+	return CollectWithTimeout(collector, DefaultCollectTimeout)
+}
+
+// CollectWithTimeout collects all metrics from the provided Collector. If the Collector doesn't finish collecting
+// within the specified timeout, CollectWithTimeout returns an error.
+//
+// If CollectWithTimeout returns early (because of an invalid metric, or a timeout), it keeps draining the metrics
+// in the background, so the Collector's Collect method can complete. Note: the goroutine running Collect can't be
+// stopped. If Collect hangs, it will leak.
+func CollectWithTimeout(collector prometheus.Collector, timeout time.Duration) (metrics Metrics, err error) {
+	ch := make(chan prometheus.Metric)
+	go func() {
+		collector.Collect(ch)
+		close(ch)
+	}()
+
+	metrics = make(Metrics)
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case metric, ok := <-ch:
+			if !ok {
+				return metrics, nil
+			}
+			var family *pcg.MetricFamily
+			if family, err = gather(metric); err != nil {
+				go drain(ch)
+				return nil, fmt.Errorf("metric %s: %w", metric.Desc(), err)
+			}
+			m := family.GetMetric()[0]
+			metrics[MetricKey(family.GetName(), labelsOf(m))] = m
+		case <-deadline.C:
+			go drain(ch)
+			return nil, fmt.Errorf("collect timed out after %s (%d metrics collected)", timeout, len(metrics))
+		}
+	}
+}
+
+// drain discards the remaining metrics, until the channel is closed
+func drain(ch <-chan prometheus.Metric) {
+	for range ch {
+	}
+}
+
+// MetricKey returns the key of a metric in Metrics: the metric's name, followed by its labels, sorted by name.
+// E.g. foo_errors_total{endpoint="/foo",method="GET"}. If the metric has no labels, the key is the metric's name.
+func MetricKey(name string, labels prometheus.Labels) string {
+	if len(labels) == 0 {
+		return name
+	}
+	names := make([]string, 0, len(labels))
+	for label := range labels {
+		names = append(names, label)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	key.WriteString(name)
+	key.WriteByte('{')
+	for index, label := range names {
+		if index > 0 {
+			key.WriteByte(',')
+		}
+		key.WriteString(label + "=" + strconv.Quote(labels[label]))
+	}
+	key.WriteByte('}')
+	return key.String()
+}
+
+func labelsOf(m *pcg.Metric) prometheus.Labels {
+	labels := make(prometheus.Labels)
+	for _, label := range m.GetLabel() {
+		labels[label.GetName()] = label.GetValue()
+	}
+	return labels
+}
+
+// Get returns the metric with the specified name and labels. The labels must include all the metric's labels,
+// i.e. both its constant and its variable labels.
+func (m Metrics) Get(name string, labels prometheus.Labels) (*pcg.Metric, error) {
+	key := MetricKey(name, labels)
+	metric, ok := m[key]
+	if !ok {
+		return nil, fmt.Errorf("metric not found: %s", key)
+	}
+	return metric, nil
+}
+
+// Counter returns the value of the specified counter
+func (m Metrics) Counter(name string, labels prometheus.Labels) (float64, error) {
+	metric, err := m.Get(name, labels)
+	if err == nil && metric.Counter == nil {
+		err = fmt.Errorf("%s is not a counter", MetricKey(name, labels))
+	}
+	return metric.GetCounter().GetValue(), err
+}
+
+// Gauge returns the value of the specified gauge
+func (m Metrics) Gauge(name string, labels prometheus.Labels) (float64, error) {
+	metric, err := m.Get(name, labels)
+	if err == nil && metric.Gauge == nil {
+		err = fmt.Errorf("%s is not a gauge", MetricKey(name, labels))
+	}
+	return metric.GetGauge().GetValue(), err
+}
+
+// Summary returns the value of the specified summary
+func (m Metrics) Summary(name string, labels prometheus.Labels) (*pcg.Summary, error) {
+	metric, err := m.Get(name, labels)
+	if err == nil && metric.Summary == nil {
+		err = fmt.Errorf("%s is not a summary", MetricKey(name, labels))
+	}
+	return metric.GetSummary(), err
+}
+
+// Histogram returns the value of the specified histogram
+func (m Metrics) Histogram(name string, labels prometheus.Labels) (*pcg.Histogram, error) {
+	metric, err := m.Get(name, labels)
+	if err == nil && metric.Histogram == nil {
+		err = fmt.Errorf("%s is not a histogram", MetricKey(name, labels))
+	}
+	return metric.GetHistogram(), err
+}
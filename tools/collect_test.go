@@ -0,0 +1,91 @@
+package tools_test
+
+import (
+	"errors"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestCollect(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "foo_total", Help: "foo"}, []string{"labelA", "labelB"})
+	counter.WithLabelValues("a", "b").Add(2)
+	counter.WithLabelValues("a", "c").Add(3)
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "bar", Help: "bar", ConstLabels: prometheus.Labels{"labelC": "c"}})
+	gauge.Set(4)
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "snafu_seconds", Help: "snafu"})
+	summary.Observe(1)
+	summary.Observe(2)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "fubar_seconds", Help: "fubar"})
+	histogram.Observe(0.2)
+
+	metrics, err := tools.Collect(collectors{counter, gauge, summary, histogram})
+	require.NoError(t, err)
+	assert.Len(t, metrics, 5)
+
+	value, err := metrics.Counter("foo_total", prometheus.Labels{"labelB": "c", "labelA": "a"})
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, value)
+
+	value, err = metrics.Gauge("bar", prometheus.Labels{"labelC": "c"})
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, value)
+
+	s, err := metrics.Summary("snafu_seconds", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), s.GetSampleCount())
+	assert.Equal(t, 3.0, s.GetSampleSum())
+
+	h, err := metrics.Histogram("fubar_seconds", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), h.GetSampleCount())
+
+	_, err = metrics.Gauge("foo_total", prometheus.Labels{"labelA": "a", "labelB": "b"})
+	assert.EqualError(t, err, `foo_total{labelA="a",labelB="b"} is not a gauge`)
+
+	_, err = metrics.Counter("foo_total", prometheus.Labels{"labelA": "a"})
+	assert.EqualError(t, err, `metric not found: foo_total{labelA="a"}`)
+}
+
+func TestCollectWithTimeout(t *testing.T) {
+	_, err := tools.CollectWithTimeout(hangingCollector{}, 10*time.Millisecond)
+	assert.EqualError(t, err, "collect timed out after 10ms (1 metrics collected)")
+}
+
+func TestCollect_Invalid(t *testing.T) {
+	done := make(chan struct{})
+	_, err := tools.Collect(invalidCollector{done: done})
+	assert.Error(t, err)
+
+	// the Collector's remaining metrics are drained, so it completes
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("collector did not complete")
+	}
+}
+
+type invalidCollector struct {
+	done chan struct{}
+}
+
+func (c invalidCollector) Describe(_ chan<- *prometheus.Desc) {}
+
+func (c invalidCollector) Collect(ch chan<- prometheus.Metric) {
+	desc := prometheus.NewDesc("foo", "foo", nil, nil)
+	ch <- prometheus.NewInvalidMetric(desc, errors.New("invalid"))
+	ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1)
+	close(c.done)
+}
+
+type hangingCollector struct{}
+
+func (h hangingCollector) Describe(_ chan<- *prometheus.Desc) {}
+
+func (h hangingCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- prometheus.MustNewConstMetric(prometheus.NewDesc("foo", "foo", nil, nil), prometheus.GaugeValue, 1)
+	time.Sleep(time.Second)
+}
@@ -0,0 +1,232 @@
+package tools
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	pcg "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CompareOptions alters the behaviour of CompareExposition
+type CompareOptions struct {
+	// IgnoreMetrics contains the names of metrics to ignore
+	IgnoreMetrics []string
+	// IgnoreLabels contains the names of labels to ignore. If two series only differ in ignored labels,
+	// CompareExposition returns an error
+	IgnoreLabels []string
+	// Delta is the maximum difference allowed between an expected and a collected value
+	Delta float64
+	// IgnoreQuantiles ignores the quantile values of summaries. Their count and sum are still compared
+	IgnoreQuantiles bool
+}
+
+// CompareExposition collects the metrics of the provided Collector and compares them to the expected metrics,
+// in Prometheus text exposition format. Only the metrics present in the expected text are compared. For each of those
+// metrics, the help text, type and all samples must match. If they don't, the returned error contains a diff
+// of the mismatched samples.
+//
+//	err := tools.CompareExposition(c, `
+//	# HELP foo_requests_total Number of requests
+//	# TYPE foo_requests_total counter
+//	foo_requests_total{endpoint="/foo"} 2
+//	`, tools.CompareOptions{})
+func CompareExposition(collector prometheus.Collector, expected string, options CompareOptions) error {
+	var parser expfmt.TextParser
+	want, err := parser.TextToMetricFamilies(strings.NewReader(expected))
+	if err != nil {
+		return fmt.Errorf("invalid expected metrics: %w", err)
+	}
+
+	r := prometheus.NewRegistry()
+	if err = r.Register(collector); err != nil {
+		return fmt.Errorf("register: %w", err)
+	}
+	var families []*pcg.MetricFamily
+	if families, err = r.Gather(); err != nil {
+		return fmt.Errorf("gather: %w", err)
+	}
+	got := make(map[string]*pcg.MetricFamily)
+	for _, family := range families {
+		got[family.GetName()] = family
+	}
+
+	ignored := make(map[string]struct{})
+	for _, name := range options.IgnoreMetrics {
+		ignored[name] = struct{}{}
+	}
+
+	names := make([]string, 0, len(want))
+	for name := range want {
+		if _, ok := ignored[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diff strings.Builder
+	for _, name := range names {
+		var familyDiff string
+		if familyDiff, err = options.compareFamily(want[name], got[name]); err != nil {
+			return err
+		}
+		diff.WriteString(familyDiff)
+	}
+	if diff.Len() > 0 {
+		return fmt.Errorf("metrics do not match (-expected +collected):\n%s", diff.String())
+	}
+	return nil
+}
+
+// TestingT is the subset of testing.T used by the test helpers of this module, like AssertExposition,
+// stubserver's AssertCalls and mock's AssertExpectations
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// AssertExposition is a test helper that calls CompareExposition and reports any differences as a test failure.
+// It returns true if the metrics match:
+//
+//	tools.AssertExposition(t, c, expected, tools.CompareOptions{Delta: 0.01})
+func AssertExposition(t TestingT, collector prometheus.Collector, expected string, options CompareOptions) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+	if err := CompareExposition(collector, expected, options); err != nil {
+		t.Errorf("%s", err.Error())
+		return false
+	}
+	return true
+}
+
+func (o CompareOptions) compareFamily(want, got *pcg.MetricFamily) (string, error) {
+	var diff strings.Builder
+	if got == nil {
+		diff.WriteString("- # TYPE " + want.GetName() + " " + strings.ToLower(want.GetType().String()) + "\n")
+		diff.WriteString("+ <metric not found>\n")
+		return diff.String(), nil
+	}
+	if want.GetHelp() != got.GetHelp() {
+		diff.WriteString("- # HELP " + want.GetName() + " " + want.GetHelp() + "\n")
+		diff.WriteString("+ # HELP " + got.GetName() + " " + got.GetHelp() + "\n")
+	}
+	if want.GetType() != got.GetType() {
+		diff.WriteString("- # TYPE " + want.GetName() + " " + strings.ToLower(want.GetType().String()) + "\n")
+		diff.WriteString("+ # TYPE " + got.GetName() + " " + strings.ToLower(got.GetType().String()) + "\n")
+	}
+
+	wantSamples, err := o.samples(want)
+	if err != nil {
+		return "", fmt.Errorf("expected metrics: %w", err)
+	}
+	gotSamples, err := o.samples(got)
+	if err != nil {
+		return "", fmt.Errorf("collected metrics: %w", err)
+	}
+
+	keys := make(map[string]struct{})
+	for key := range wantSamples {
+		keys[key] = struct{}{}
+	}
+	for key := range gotSamples {
+		keys[key] = struct{}{}
+	}
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	for _, key := range sortedKeys {
+		wantValue, wantFound := wantSamples[key]
+		gotValue, gotFound := gotSamples[key]
+		if wantFound && gotFound && o.equal(wantValue, gotValue) {
+			continue
+		}
+		if wantFound {
+			diff.WriteString("- " + key + " " + formatValue(wantValue) + "\n")
+		}
+		if gotFound {
+			diff.WriteString("+ " + key + " " + formatValue(gotValue) + "\n")
+		}
+	}
+	return diff.String(), nil
+}
+
+func (o CompareOptions) equal(a, b float64) bool {
+	if math.IsNaN(a) && math.IsNaN(b) {
+		return true
+	}
+	return a == b || math.Abs(a-b) <= o.Delta
+}
+
+// samples flattens a metric family into its individual samples, as they would appear in the text exposition format.
+// It returns an error if two series of the family have the same labels, once IgnoreLabels are removed.
+func (o CompareOptions) samples(family *pcg.MetricFamily) (map[string]float64, error) {
+	samples := make(map[string]float64)
+	series := make(map[string]struct{})
+	name := family.GetName()
+	for _, m := range family.GetMetric() {
+		labels := o.labels(m)
+		key := MetricKey(name, labels)
+		if _, found := series[key]; found {
+			return nil, fmt.Errorf("multiple series match %s once labels %v are ignored", key, o.IgnoreLabels)
+		}
+		series[key] = struct{}{}
+		switch family.GetType() {
+		case pcg.MetricType_COUNTER:
+			samples[MetricKey(name, labels)] = m.GetCounter().GetValue()
+		case pcg.MetricType_GAUGE:
+			samples[MetricKey(name, labels)] = m.GetGauge().GetValue()
+		case pcg.MetricType_SUMMARY:
+			if !o.IgnoreQuantiles {
+				for _, q := range m.GetSummary().GetQuantile() {
+					samples[MetricKey(name, withLabel(labels, "quantile", formatValue(q.GetQuantile())))] = q.GetValue()
+				}
+			}
+			samples[MetricKey(name+"_sum", labels)] = m.GetSummary().GetSampleSum()
+			samples[MetricKey(name+"_count", labels)] = float64(m.GetSummary().GetSampleCount())
+		case pcg.MetricType_HISTOGRAM:
+			for _, b := range m.GetHistogram().GetBucket() {
+				samples[MetricKey(name+"_bucket", withLabel(labels, "le", formatValue(b.GetUpperBound())))] = float64(b.GetCumulativeCount())
+			}
+			samples[MetricKey(name+"_bucket", withLabel(labels, "le", "+Inf"))] = float64(m.GetHistogram().GetSampleCount())
+			samples[MetricKey(name+"_sum", labels)] = m.GetHistogram().GetSampleSum()
+			samples[MetricKey(name+"_count", labels)] = float64(m.GetHistogram().GetSampleCount())
+		default:
+			samples[MetricKey(name, labels)] = m.GetUntyped().GetValue()
+		}
+	}
+	return samples, nil
+}
+
+func (o CompareOptions) labels(m *pcg.Metric) prometheus.Labels {
+	labels := labelsOf(m)
+	for _, label := range o.IgnoreLabels {
+		delete(labels, label)
+	}
+	return labels
+}
+
+func withLabel(labels prometheus.Labels, name, value string) prometheus.Labels {
+	result := make(prometheus.Labels, len(labels)+1)
+	for k, v := range labels {
+		result[k] = v
+	}
+	result[name] = value
+	return result
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(value, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,121 @@
+package tools_test
+
+import (
+	"github.com/clambin/go-metrics/internal/testutil"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompareExposition(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "foo_requests_total", Help: "Number of requests"}, []string{"endpoint", "instance"})
+	counter.WithLabelValues("/foo", "host1").Add(2)
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{
+		Name:       "foo_request_duration_seconds",
+		Help:       "Duration of requests",
+		Objectives: map[float64]float64{0.5: 0.05},
+	})
+	summary.Observe(0.25)
+	summary.Observe(0.5)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "foo_size_bytes", Help: "Size", Buckets: []float64{10, 100}})
+	histogram.Observe(50)
+	c := collectors{counter, summary, histogram}
+
+	err := tools.CompareExposition(c, `
+# HELP foo_requests_total Number of requests
+# TYPE foo_requests_total counter
+foo_requests_total{endpoint="/foo",instance="host1"} 2
+# HELP foo_request_duration_seconds Duration of requests
+# TYPE foo_request_duration_seconds summary
+foo_request_duration_seconds{quantile="0.5"} 0.25
+foo_request_duration_seconds_sum 0.75
+foo_request_duration_seconds_count 2
+# HELP foo_size_bytes Size
+# TYPE foo_size_bytes histogram
+foo_size_bytes_bucket{le="10"} 0
+foo_size_bytes_bucket{le="100"} 1
+foo_size_bytes_bucket{le="+Inf"} 1
+foo_size_bytes_sum 50
+foo_size_bytes_count 1
+`, tools.CompareOptions{})
+	assert.NoError(t, err)
+
+	err = tools.CompareExposition(c, `
+# HELP foo_requests_total Number of requests
+# TYPE foo_requests_total counter
+foo_requests_total{endpoint="/foo"} 2.01
+# HELP foo_request_duration_seconds Duration of requests
+# TYPE foo_request_duration_seconds summary
+foo_request_duration_seconds{quantile="0.9"} 1
+foo_request_duration_seconds_sum 0.75
+foo_request_duration_seconds_count 2
+`, tools.CompareOptions{IgnoreLabels: []string{"instance"}, Delta: 0.1, IgnoreQuantiles: true})
+	assert.NoError(t, err)
+
+	err = tools.CompareExposition(c, `
+# HELP foo_requests_total Number of HTTP requests
+# TYPE foo_requests_total counter
+foo_requests_total{endpoint="/foo",instance="host1"} 3
+foo_requests_total{endpoint="/bar",instance="host1"} 1
+# HELP foo_missing Missing metric
+# TYPE foo_missing gauge
+foo_missing 1
+# HELP foo_ignored Ignored metric
+# TYPE foo_ignored gauge
+foo_ignored 1
+`, tools.CompareOptions{IgnoreMetrics: []string{"foo_ignored"}})
+	require.Error(t, err)
+	assert.Equal(t, `metrics do not match (-expected +collected):
+- # TYPE foo_missing gauge
++ <metric not found>
+- # HELP foo_requests_total Number of HTTP requests
++ # HELP foo_requests_total Number of requests
+- foo_requests_total{endpoint="/bar",instance="host1"} 1
+- foo_requests_total{endpoint="/foo",instance="host1"} 3
++ foo_requests_total{endpoint="/foo",instance="host1"} 2
+`, err.Error())
+
+	err = tools.CompareExposition(c, `invalid`, tools.CompareOptions{})
+	assert.Error(t, err)
+}
+
+func TestCompareExposition_IgnoreLabelsCollision(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "foo_requests_total", Help: "Number of requests"}, []string{"endpoint", "instance"})
+	counter.WithLabelValues("/foo", "host1").Add(2)
+	counter.WithLabelValues("/foo", "host2").Add(3)
+
+	// both series become foo_requests_total{endpoint="/foo"}: the comparison is ambiguous
+	for _, value := range []string{"2", "3"} {
+		err := tools.CompareExposition(counter, `
+# HELP foo_requests_total Number of requests
+# TYPE foo_requests_total counter
+foo_requests_total{endpoint="/foo"} `+value+`
+`, tools.CompareOptions{IgnoreLabels: []string{"instance"}})
+		require.Error(t, err)
+		assert.Equal(t, `collected metrics: multiple series match foo_requests_total{endpoint="/foo"} once labels [instance] are ignored`, err.Error())
+	}
+}
+
+func TestAssertExposition(t *testing.T) {
+	gauge := prometheus.NewGauge(prometheus.GaugeOpts{Name: "foo", Help: "foo"})
+	gauge.Set(1)
+
+	assert.True(t, tools.AssertExposition(t, gauge, `
+# HELP foo foo
+# TYPE foo gauge
+foo 1
+`, tools.CompareOptions{}))
+
+	var f testutil.FakeT
+	assert.False(t, tools.AssertExposition(&f, gauge, `
+# HELP foo foo
+# TYPE foo gauge
+foo 2
+`, tools.CompareOptions{}))
+	assert.Equal(t, []string{`metrics do not match (-expected +collected):
+- foo 2
++ foo 1
+`}, f.Errors)
+}
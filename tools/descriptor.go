@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	pcg "github.com/prometheus/client_model/go"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Descriptor describes a Prometheus metric
+type Descriptor struct {
+	// Name is the fully-qualified name of the metric
+	Name string
+	// Help is the metric's help text
+	Help string
+	// Type is the metric's type. When describing a Collector, this is only known for metrics that the Collector
+	// collected. Otherwise, it's set to pcg.MetricType_UNTYPED.
+	Type pcg.MetricType
+	// ConstLabels contains the metric's constant labels
+	ConstLabels prometheus.Labels
+	// VariableLabels contains the names of the metric's variable labels
+	VariableLabels []string
+}
+
+// MetricDescriptor returns the Descriptor of the provided Prometheus metric:
+//
+//	ch := make(chan prometheus.Metric)
+//	go c.Collect(ch)
+//
+//	d, err := tools.MetricDescriptor(<-ch)
+//	assert.Equal(t, "foo_bar_total", d.Name)
+//	assert.Equal(t, pcg.MetricType_COUNTER, d.Type)
+func MetricDescriptor(metric prometheus.Metric) (descriptor Descriptor, err error) {
+	if descriptor, err = describe(metric.Desc()); err != nil {
+		return
+	}
+	var family *pcg.MetricFamily
+	if family, err = gather(metric); err != nil {
+		return descriptor, fmt.Errorf("metric %s: %w", descriptor.Name, err)
+	}
+	descriptor.Type = family.GetType()
+	return
+}
+
+// CollectorDescriptors returns the Descriptor of each metric described by the provided Collector, sorted by name.
+// The metrics' types are determined by gathering the Collector's metrics. Metrics that the Collector describes,
+// but does not currently collect (e.g. a CounterVec without any label values) are reported as pcg.MetricType_UNTYPED.
+func CollectorDescriptors(collector prometheus.Collector) (descriptors []Descriptor, err error) {
+	r := prometheus.NewRegistry()
+	if err = r.Register(collector); err != nil {
+		return
+	}
+	var families []*pcg.MetricFamily
+	if families, err = r.Gather(); err != nil {
+		return
+	}
+	types := make(map[string]pcg.MetricType)
+	for _, family := range families {
+		types[family.GetName()] = family.GetType()
+	}
+
+	ch := make(chan *prometheus.Desc)
+	go func() {
+		collector.Describe(ch)
+		close(ch)
+	}()
+
+	for desc := range ch {
+		descriptor, err2 := describe(desc)
+		if err2 != nil {
+			// drain the channel so the Describe goroutine can finish
+			for range ch {
+			}
+			return nil, err2
+		}
+		if t, ok := types[descriptor.Name]; ok {
+			descriptor.Type = t
+		}
+		descriptors = append(descriptors, descriptor)
+	}
+	sort.Slice(descriptors, func(i, j int) bool { return descriptors[i].Name < descriptors[j].Name })
+	return
+}
+
+// gather returns the MetricFamily of the provided metric, as gathered by a Registry
+func gather(metric prometheus.Metric) (*pcg.MetricFamily, error) {
+	r := prometheus.NewRegistry()
+	if err := r.Register(metricCollector{Metric: metric}); err != nil {
+		return nil, err
+	}
+	families, err := r.Gather()
+	if err != nil {
+		return nil, err
+	}
+	if len(families) != 1 || len(families[0].GetMetric()) != 1 {
+		return nil, errors.New("metric not gathered")
+	}
+	return families[0], nil
+}
+
+// metricCollector is a Collector for a single metric
+type metricCollector struct {
+	prometheus.Metric
+}
+
+func (m metricCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- m.Desc()
+}
+
+func (m metricCollector) Collect(ch chan<- prometheus.Metric) {
+	ch <- m.Metric
+}
+
+// maxVariableLabels is the highest number of variable labels that describe supports
+const maxVariableLabels = 256
+
+// probePrefix marks the label values of a probe metric
+const probePrefix = "\x00probe-"
+
+// describe returns the Descriptor of a prometheus.Desc, with an untyped Type. prometheus.Desc doesn't expose its
+// fields, so describe creates a probe metric for the Desc, with a unique value for each variable label, and gathers it.
+// The labels with a probe value are the variable labels. All others are constant labels.
+func describe(desc *prometheus.Desc) (descriptor Descriptor, err error) {
+	var probe prometheus.Metric
+	var count int
+	if probe, count, err = probeMetric(desc); err != nil {
+		return descriptor, fmt.Errorf("invalid descriptor %s: %w", desc, err)
+	}
+	var family *pcg.MetricFamily
+	if family, err = gather(probe); err != nil {
+		return descriptor, fmt.Errorf("invalid descriptor %s: %w", desc, err)
+	}
+
+	descriptor.Name = family.GetName()
+	descriptor.Help = family.GetHelp()
+	descriptor.Type = pcg.MetricType_UNTYPED
+	descriptor.ConstLabels = make(prometheus.Labels)
+	descriptor.VariableLabels = make([]string, count)
+	for _, label := range family.GetMetric()[0].GetLabel() {
+		if index, ok := probeIndex(label.GetValue()); ok && index < count {
+			descriptor.VariableLabels[index] = label.GetName()
+		} else {
+			descriptor.ConstLabels[label.GetName()] = label.GetValue()
+		}
+	}
+	return
+}
+
+// probeMetric creates a metric for the Desc, trying each number of variable labels until the Desc accepts the
+// label values. It returns the metric and the number of variable labels.
+func probeMetric(desc *prometheus.Desc) (metric prometheus.Metric, count int, err error) {
+	var values []string
+	for count = 0; count <= maxVariableLabels; count++ {
+		var err2 error
+		if metric, err2 = prometheus.NewConstMetric(desc, prometheus.UntypedValue, 0, values...); err2 == nil {
+			return metric, count, nil
+		}
+		if count == 0 {
+			err = err2
+		}
+		values = append(values, probePrefix+strconv.Itoa(count))
+	}
+	return nil, 0, err
+}
+
+func probeIndex(value string) (int, bool) {
+	if !strings.HasPrefix(value, probePrefix) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(value, probePrefix))
+	return index, err == nil
+}
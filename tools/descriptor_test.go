@@ -0,0 +1,90 @@
+package tools_test
+
+import (
+	"errors"
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	pcg "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMetricDescriptor(t *testing.T) {
+	desc := prometheus.NewDesc(
+		"job:http2_requests:rate5m",
+		`Rate of "HTTP/2" requests`,
+		[]string{"labelA", "labelB"},
+		prometheus.Labels{"labelC": "value,C", "labelD": `value="D"`},
+	)
+
+	m := prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 1.0, "valueA", "valueB")
+	assert.Equal(t, "job:http2_requests:rate5m", tools.MetricName(m))
+
+	d, err := tools.MetricDescriptor(m)
+	require.NoError(t, err)
+	assert.Equal(t, tools.Descriptor{
+		Name:           "job:http2_requests:rate5m",
+		Help:           `Rate of "HTTP/2" requests`,
+		Type:           pcg.MetricType_GAUGE,
+		ConstLabels:    prometheus.Labels{"labelC": "value,C", "labelD": `value="D"`},
+		VariableLabels: []string{"labelA", "labelB"},
+	}, d)
+}
+
+func TestCollectorDescriptors(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http2_requests_total",
+		Help: "Number of HTTP/2 requests",
+	}, []string{"method"})
+	counter.WithLabelValues("GET").Inc()
+	summary := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+		Name: "http2_request_duration_seconds",
+		Help: "Duration of HTTP/2 requests",
+	}, []string{"method"})
+
+	descriptors, err := tools.CollectorDescriptors(collectors{counter, summary})
+	require.NoError(t, err)
+	assert.Equal(t, []tools.Descriptor{
+		{
+			Name:           "http2_request_duration_seconds",
+			Help:           "Duration of HTTP/2 requests",
+			Type:           pcg.MetricType_UNTYPED,
+			ConstLabels:    prometheus.Labels{},
+			VariableLabels: []string{"method"},
+		},
+		{
+			Name:           "http2_requests_total",
+			Help:           "Number of HTTP/2 requests",
+			Type:           pcg.MetricType_COUNTER,
+			ConstLabels:    prometheus.Labels{},
+			VariableLabels: []string{"method"},
+		},
+	}, descriptors)
+}
+
+type collectors []prometheus.Collector
+
+func (c collectors) Describe(ch chan<- *prometheus.Desc) {
+	for _, collector := range c {
+		collector.Describe(ch)
+	}
+}
+
+func (c collectors) Collect(ch chan<- prometheus.Metric) {
+	for _, collector := range c {
+		collector.Collect(ch)
+	}
+}
+
+func TestMetricDescriptor_Invalid(t *testing.T) {
+	desc := prometheus.NewDesc("invalid name", "help", nil, nil)
+	m := prometheus.NewInvalidMetric(desc, errors.New("invalid"))
+	assert.Empty(t, tools.MetricName(m))
+
+	_, err := tools.MetricDescriptor(m)
+	assert.Error(t, err)
+
+	_, err = tools.CollectorDescriptors(collectors{prometheus.NewCounter(prometheus.CounterOpts{Name: "invalid name"})})
+	assert.Error(t, err)
+}
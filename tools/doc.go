@@ -15,5 +15,38 @@ MetricName, MetricLabel and MetricValue provide an easy way to validate metrics
 		assert.Equal(t, "valueA", metrics.MetricLabel(m, "labelA"))
 	}
 
+Collect collects all metrics of a Collector, without having to know up front how many metrics it produces.
+The returned Metrics provide typed access to each metric's value:
+
+	m, err := tools.Collect(yourCollector)
+	require.NoError(t, err)
+	value, err := m.Gauge("foo_bar_gauge", prometheus.Labels{"labelA": "valueA"})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+
+AssertExposition compares the metrics of a Collector to their expected text exposition:
+
+	tools.AssertExposition(t, yourCollector, `
+	# HELP foo_bar_gauge Dummy metric
+	# TYPE foo_bar_gauge gauge
+	foo_bar_gauge{labelA="valueA"} 1
+	`, tools.CompareOptions{IgnoreLabels: []string{"instance"}})
+
+Lint checks the metrics of a Collector against Prometheus' naming conventions and best practices:
+
+	findings, err := tools.Lint(yourCollector, tools.LintOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+
+Scrape reads the metrics of an HTTP metrics endpoint, in Prometheus text or OpenMetrics format. Parse does the same
+for an io.Reader:
+
+	families, err := tools.Scrape("http://127.0.0.1:8080/metrics")
+	require.NoError(t, err)
+	value, err := families.Value("foo_bar_gauge", prometheus.Labels{"labelA": "valueA"})
+
+MetricDescriptor and CollectorDescriptors return a metric's name, help text, type, constant labels and variable labels
+as a Descriptor.
+
 */
 package tools
@@ -0,0 +1,250 @@
+package tools
+
+import (
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	pcg "github.com/prometheus/client_model/go"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Finding is a single problem reported by Lint
+type Finding struct {
+	// Metric is the name of the metric
+	Metric string
+	// Rule is the name of the rule that reported the problem
+	Rule string
+	// Message describes the problem
+	Message string
+}
+
+// String returns a readable version of the finding
+func (f Finding) String() string {
+	return f.Metric + ": " + f.Message + " (" + f.Rule + ")"
+}
+
+// Lint rules
+const (
+	// RuleHelp reports metrics without help text
+	RuleHelp = "help"
+	// RuleSnakeCase reports metric and label names that are not in snake_case
+	RuleSnakeCase = "snake-case"
+	// RuleCounterTotal reports counters without a "_total" suffix, and other metrics with a "_total" suffix
+	RuleCounterTotal = "counter-total"
+	// RuleUnitSuffix reports metrics that measure time without a "_seconds" suffix, or that use non-base units
+	RuleUnitSuffix = "unit-suffix"
+	// RuleReservedLabel reports labels that are reserved by Prometheus
+	RuleReservedLabel = "reserved-label"
+	// RuleCardinality reports metrics with more series than LintOptions.MaxSeries
+	RuleCardinality = "cardinality"
+)
+
+// LintOptions alters the behaviour of Lint
+type LintOptions struct {
+	// IgnoreRules contains the rules that should not be checked
+	IgnoreRules []string
+	// MaxSeries is the maximum number of series a metric should have. If zero, DefaultMaxSeries is used
+	MaxSeries int
+}
+
+// DefaultMaxSeries is the default maximum number of series per metric reported by RuleCardinality
+const DefaultMaxSeries = 100
+
+// Lint checks the metrics of the provided Collector against Prometheus' naming conventions and best practices:
+//
+//	findings, err := tools.Lint(yourCollector, tools.LintOptions{})
+//	require.NoError(t, err)
+//	assert.Empty(t, findings)
+//
+// Label cardinality is estimated from the series the Collector currently produces.
+func Lint(collector prometheus.Collector, options LintOptions) (findings []Finding, err error) {
+	var descriptors []Descriptor
+	if descriptors, err = CollectorDescriptors(collector); err != nil {
+		return
+	}
+	var metrics Metrics
+	if metrics, err = Collect(collector); err != nil {
+		return
+	}
+	series := make(map[string]int)
+	for key := range metrics {
+		name, _, _ := strings.Cut(key, "{")
+		series[name]++
+	}
+
+	targets := make([]lintTarget, 0, len(descriptors))
+	for _, d := range descriptors {
+		labels := append([]string{}, d.VariableLabels...)
+		for label := range d.ConstLabels {
+			labels = append(labels, label)
+		}
+		targets = append(targets, lintTarget{name: d.Name, help: d.Help, metricType: d.Type, labels: labels, series: series[d.Name]})
+	}
+	return options.lint(targets), nil
+}
+
+// LintGatherer checks the metrics gathered by the provided Gatherer (e.g. a prometheus.Registry). Contrary to Lint,
+// LintGatherer only sees metrics that currently have at least one series.
+func LintGatherer(gatherer prometheus.Gatherer, options LintOptions) (findings []Finding, err error) {
+	var families []*pcg.MetricFamily
+	if families, err = gatherer.Gather(); err != nil {
+		return
+	}
+
+	targets := make([]lintTarget, 0, len(families))
+	for _, family := range families {
+		labels := make(map[string]struct{})
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				labels[label.GetName()] = struct{}{}
+			}
+		}
+		target := lintTarget{name: family.GetName(), help: family.GetHelp(), metricType: family.GetType(), series: len(family.GetMetric())}
+		for label := range labels {
+			target.labels = append(target.labels, label)
+		}
+		targets = append(targets, target)
+	}
+	return options.lint(targets), nil
+}
+
+type lintTarget struct {
+	name       string
+	help       string
+	metricType pcg.MetricType
+	labels     []string
+	series     int
+}
+
+type lintRule struct {
+	name  string
+	check func(target lintTarget, options LintOptions) []string
+}
+
+var lintRules = []lintRule{
+	{name: RuleHelp, check: checkHelp},
+	{name: RuleSnakeCase, check: checkSnakeCase},
+	{name: RuleCounterTotal, check: checkCounterTotal},
+	{name: RuleUnitSuffix, check: checkUnitSuffix},
+	{name: RuleReservedLabel, check: checkReservedLabels},
+	{name: RuleCardinality, check: checkCardinality},
+}
+
+func (o LintOptions) lint(targets []lintTarget) (findings []Finding) {
+	ignored := make(map[string]struct{})
+	for _, rule := range o.IgnoreRules {
+		ignored[rule] = struct{}{}
+	}
+	for _, target := range targets {
+		sort.Strings(target.labels)
+		for _, rule := range lintRules {
+			if _, ok := ignored[rule.name]; ok {
+				continue
+			}
+			for _, message := range rule.check(target, o) {
+				findings = append(findings, Finding{Metric: target.name, Rule: rule.name, Message: message})
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Metric < findings[j].Metric })
+	return
+}
+
+func checkHelp(target lintTarget, _ LintOptions) []string {
+	if strings.TrimSpace(target.help) == "" {
+		return []string{"no help text"}
+	}
+	return nil
+}
+
+var (
+	snakeCaseMetric = regexp.MustCompile(`^[a-z_:][a-z0-9_:]*$`)
+	snakeCaseLabel  = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+func checkSnakeCase(target lintTarget, _ LintOptions) (messages []string) {
+	if !snakeCaseMetric.MatchString(target.name) || strings.Contains(target.name, "__") {
+		messages = append(messages, "metric name is not in snake_case")
+	}
+	for _, label := range target.labels {
+		if !snakeCaseLabel.MatchString(strings.TrimPrefix(label, "__")) {
+			messages = append(messages, fmt.Sprintf("label %q is not in snake_case", label))
+		}
+	}
+	return
+}
+
+func checkCounterTotal(target lintTarget, _ LintOptions) []string {
+	hasTotal := strings.HasSuffix(target.name, "_total")
+	switch {
+	case target.metricType == pcg.MetricType_COUNTER && !hasTotal:
+		return []string{`counter should have a "_total" suffix`}
+	case target.metricType != pcg.MetricType_COUNTER && target.metricType != pcg.MetricType_UNTYPED && hasTotal:
+		return []string{`non-counter should not have a "_total" suffix`}
+	}
+	return nil
+}
+
+var (
+	timeWords     = []string{"latency", "duration", "time", "elapsed", "delay"}
+	nonBaseUnits  = []string{"milliseconds", "microseconds", "nanoseconds", "minutes", "hours", "days", "ms", "kilobytes", "megabytes", "gigabytes", "percent"}
+	baseUnitHints = map[string]string{"percent": "ratio"}
+)
+
+func checkUnitSuffix(target lintTarget, _ LintOptions) (messages []string) {
+	parts := strings.Split(strings.TrimSuffix(target.name, "_total"), "_")
+	for _, part := range parts {
+		for _, unit := range nonBaseUnits {
+			if part == unit {
+				hint := baseUnitHints[unit]
+				if hint == "" {
+					hint = "a base unit (e.g. seconds, bytes)"
+				}
+				messages = append(messages, fmt.Sprintf("metric uses non-base unit %q: use %s instead", unit, hint))
+			}
+		}
+	}
+	if parts[len(parts)-1] == "seconds" || (target.metricType == pcg.MetricType_COUNTER && strings.HasSuffix(target.name, "_seconds_total")) {
+		return
+	}
+	for _, part := range parts {
+		for _, word := range timeWords {
+			if part == word {
+				return append(messages, `metric measures time, but has no "_seconds" suffix`)
+			}
+		}
+	}
+	return
+}
+
+var reservedLabels = map[string]pcg.MetricType{
+	"job":      -1,
+	"instance": -1,
+	"le":       pcg.MetricType_HISTOGRAM,
+	"quantile": pcg.MetricType_SUMMARY,
+}
+
+func checkReservedLabels(target lintTarget, _ LintOptions) (messages []string) {
+	for _, label := range target.labels {
+		if strings.HasPrefix(label, "__") {
+			messages = append(messages, fmt.Sprintf("label %q is reserved for internal use", label))
+			continue
+		}
+		if allowedFor, ok := reservedLabels[label]; ok && allowedFor != target.metricType {
+			messages = append(messages, fmt.Sprintf("label %q is reserved by Prometheus", label))
+		}
+	}
+	return
+}
+
+func checkCardinality(target lintTarget, options LintOptions) []string {
+	maxSeries := options.MaxSeries
+	if maxSeries == 0 {
+		maxSeries = DefaultMaxSeries
+	}
+	if target.series > maxSeries {
+		return []string{fmt.Sprintf("metric has %d series (maximum: %d)", target.series, maxSeries)}
+	}
+	return nil
+}
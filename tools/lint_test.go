@@ -0,0 +1,59 @@
+package tools_test
+
+import (
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	good := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "foo_requests_total", Help: "Number of requests"}, []string{"endpoint"})
+	good.WithLabelValues("/foo").Inc()
+	latency := prometheus.NewSummaryVec(prometheus.SummaryOpts{Name: "foo_request_latency", Help: "Latency of requests"}, []string{"instance"})
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "fooRequests", Help: "Number of requests"})
+	counter.Inc()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "foo_size_megabytes_total", Help: " "}, []string{"shard"})
+	for i := 0; i < 5; i++ {
+		gauge.WithLabelValues(strconv.Itoa(i)).Set(1)
+	}
+
+	findings, err := tools.Lint(collectors{good, latency, counter, gauge}, tools.LintOptions{MaxSeries: 4})
+	require.NoError(t, err)
+
+	var output []string
+	for _, finding := range findings {
+		output = append(output, finding.String())
+	}
+	assert.Equal(t, []string{
+		`fooRequests: metric name is not in snake_case (snake-case)`,
+		`fooRequests: counter should have a "_total" suffix (counter-total)`,
+		`foo_request_latency: metric measures time, but has no "_seconds" suffix (unit-suffix)`,
+		`foo_request_latency: label "instance" is reserved by Prometheus (reserved-label)`,
+		`foo_size_megabytes_total: no help text (help)`,
+		`foo_size_megabytes_total: non-counter should not have a "_total" suffix (counter-total)`,
+		`foo_size_megabytes_total: metric uses non-base unit "megabytes": use a base unit (e.g. seconds, bytes) instead (unit-suffix)`,
+		`foo_size_megabytes_total: metric has 5 series (maximum: 4) (cardinality)`,
+	}, output)
+
+	findings, err = tools.Lint(collectors{good, latency}, tools.LintOptions{IgnoreRules: []string{tools.RuleUnitSuffix, tools.RuleReservedLabel}})
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+}
+
+func TestLintGatherer(t *testing.T) {
+	r := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "foo_load_percent", Help: "Load"}, []string{"job"})
+	gauge.WithLabelValues("a").Set(1)
+	latency := prometheus.NewSummaryVec(prometheus.SummaryOpts{Name: "foo_latency", Help: "Latency"}, []string{"endpoint"})
+	r.MustRegister(gauge, latency)
+
+	findings, err := tools.LintGatherer(r, tools.LintOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []tools.Finding{
+		{Metric: "foo_load_percent", Rule: tools.RuleUnitSuffix, Message: `metric uses non-base unit "percent": use ratio instead`},
+		{Metric: "foo_load_percent", Rule: tools.RuleReservedLabel, Message: `label "job" is reserved by Prometheus`},
+	}, findings)
+}
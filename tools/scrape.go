@@ -0,0 +1,192 @@
+package tools
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/prometheus/client_golang/prometheus"
+	pcg "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// MetricFamilies contains parsed metrics, keyed by metric name
+type MetricFamilies map[string]*pcg.MetricFamily
+
+// Scrape fetches the metrics from the provided URL (e.g. a Server's /metrics endpoint) and parses them:
+//
+//	families, err := tools.Scrape("http://127.0.0.1:8080/metrics")
+//	require.NoError(t, err)
+//	value, err := families.Value("http_duration_seconds_count", prometheus.Labels{"path": "/hello", "method": "GET", "status_code": "200"})
+//	require.NoError(t, err)
+//	assert.Equal(t, 1.0, value)
+func Scrape(url string) (MetricFamilies, error) {
+	return ScrapeWithClient(http.DefaultClient, url)
+}
+
+// ScrapeWithClient fetches the metrics from the provided URL using the provided HTTP client and parses them.
+// It accepts both the Prometheus text format and the OpenMetrics format.
+func ScrapeWithClient(client *http.Client, url string) (MetricFamilies, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", expfmt.OpenMetricsType+";version="+expfmt.OpenMetricsVersion+",text/plain;version="+expfmt.TextVersion+";q=0.5")
+
+	var resp *http.Response
+	if resp, err = client.Do(req); err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("scrape %s: %s", url, resp.Status)
+	}
+	return Parse(resp.Body, resp.Header.Get("Content-Type"))
+}
+
+// Parse parses metrics in the Prometheus text format or the OpenMetrics format. The format is determined by the
+// provided content type. If the content type is empty, the Prometheus text format is assumed.
+func Parse(r io.Reader, contentType string) (MetricFamilies, error) {
+	if strings.HasPrefix(contentType, expfmt.OpenMetricsType) {
+		text, err := openMetricsToText(r)
+		if err != nil {
+			return nil, err
+		}
+		r = strings.NewReader(text)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(r)
+	return families, err
+}
+
+// Value returns the value of a single sample, as it appears in the text format. E.g. for a summary "foo",
+// valid sample names are "foo_count", "foo_sum" and "foo" (with a "quantile" label).
+func (f MetricFamilies) Value(name string, labels prometheus.Labels) (float64, error) {
+	key := MetricKey(name, labels)
+	for _, familyName := range []string{name, strings.TrimSuffix(name, "_count"), strings.TrimSuffix(name, "_sum"), strings.TrimSuffix(name, "_bucket")} {
+		if family, ok := f[familyName]; ok {
+			samples, err := (CompareOptions{}).samples(family)
+			if err != nil {
+				return 0, err
+			}
+			if value, ok := samples[key]; ok {
+				return value, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("metric not found: %s", key)
+}
+
+// openMetricsToText converts the OpenMetrics format to the Prometheus text format, which expfmt can parse.
+func openMetricsToText(r io.Reader) (string, error) {
+	var lines []string
+	types := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "# EOF" {
+			break
+		}
+		if fields := strings.Fields(line); len(fields) == 4 && fields[0] == "#" && fields[1] == "TYPE" {
+			types[fields[2]] = fields[3]
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+
+	var output strings.Builder
+	for _, line := range lines {
+		var err error
+		if strings.HasPrefix(line, "#") {
+			line = convertOpenMetricsComment(line, types)
+		} else if line, err = convertOpenMetricsSample(line, types); err != nil {
+			return "", err
+		}
+		if line != "" {
+			output.WriteString(line + "\n")
+		}
+	}
+	return output.String(), nil
+}
+
+// openMetricsTypes maps OpenMetrics types to their Prometheus text format equivalent, and the suffix of their samples
+var openMetricsTypes = map[string]struct{ textType, suffix string }{
+	"counter":        {textType: "counter", suffix: "_total"},
+	"info":           {textType: "gauge", suffix: "_info"},
+	"stateset":       {textType: "gauge"},
+	"unknown":        {textType: "untyped"},
+	"gaugehistogram": {textType: "untyped"},
+}
+
+func convertOpenMetricsComment(line string, types map[string]string) string {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) < 3 {
+		return line
+	}
+	switch fields[1] {
+	case "UNIT":
+		return ""
+	case "HELP", "TYPE":
+		if t, ok := openMetricsTypes[types[fields[2]]]; ok {
+			fields[2] += t.suffix
+			if fields[1] == "TYPE" {
+				fields[3] = t.textType
+			}
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+func convertOpenMetricsSample(line string, types map[string]string) (string, error) {
+	// split the sample into its name & labels, and its value, timestamp & exemplar
+	end := strings.IndexAny(line, "{ ")
+	if end == -1 {
+		return "", fmt.Errorf("invalid sample: %q", line)
+	}
+	if line[end] == '{' {
+		inQuotes := false
+		for end++; end < len(line) && (inQuotes || line[end] != '}'); end++ {
+			switch line[end] {
+			case '\\':
+				end++
+			case '"':
+				inQuotes = !inQuotes
+			}
+		}
+		end++
+	}
+	if end > len(line) {
+		return "", fmt.Errorf("invalid sample: %q", line)
+	}
+
+	// counters, summaries and histograms may have a "_created" sample, which the text format doesn't support
+	name := line[:strings.IndexAny(line, "{ ")]
+	if family := strings.TrimSuffix(name, "_created"); family != name {
+		switch types[family] {
+		case "counter", "summary", "histogram":
+			return "", nil
+		}
+	}
+
+	rest, _, _ := strings.Cut(line[end:], " # ")
+	fields := strings.Fields(rest)
+	switch len(fields) {
+	case 1:
+	case 2:
+		// OpenMetrics timestamps are in seconds. The text format uses milliseconds.
+		timestamp, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid timestamp: %q", line)
+		}
+		fields[1] = strconv.FormatInt(int64(timestamp*1000), 10)
+	default:
+		return "", fmt.Errorf("invalid sample: %q", line)
+	}
+	return line[:end] + " " + strings.Join(fields, " "), nil
+}
@@ -0,0 +1,91 @@
+package tools_test
+
+import (
+	"github.com/clambin/go-metrics/tools"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScrape(t *testing.T) {
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "foo_requests_total", Help: "Number of requests"}, []string{"endpoint"})
+	counter.WithLabelValues("/foo").Add(2)
+	summary := prometheus.NewSummary(prometheus.SummaryOpts{Name: "foo_duration_seconds", Help: "Duration", Objectives: map[float64]float64{0.5: 0.05}})
+	summary.Observe(1)
+	histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "foo_size_bytes", Help: "Size", Buckets: []float64{10}})
+	histogram.Observe(5)
+	info := prometheus.NewGauge(prometheus.GaugeOpts{Name: "foo_build_info", Help: "Build info", ConstLabels: prometheus.Labels{"version": "1.0"}})
+	info.Set(1)
+	r := prometheus.NewRegistry()
+	r.MustRegister(counter, summary, histogram, info)
+
+	for _, openMetrics := range []bool{false, true} {
+		s := httptest.NewServer(promhttp.HandlerFor(r, promhttp.HandlerOpts{EnableOpenMetrics: openMetrics}))
+
+		families, err := tools.Scrape(s.URL)
+		require.NoError(t, err)
+		assert.Len(t, families, 4)
+
+		for _, tc := range []struct {
+			name   string
+			labels prometheus.Labels
+			value  float64
+		}{
+			{name: "foo_requests_total", labels: prometheus.Labels{"endpoint": "/foo"}, value: 2},
+			{name: "foo_duration_seconds", labels: prometheus.Labels{"quantile": "0.5"}, value: 1},
+			{name: "foo_duration_seconds_count", value: 1},
+			{name: "foo_size_bytes_bucket", labels: prometheus.Labels{"le": "10"}, value: 1},
+			{name: "foo_size_bytes_sum", value: 5},
+			{name: "foo_build_info", labels: prometheus.Labels{"version": "1.0"}, value: 1},
+		} {
+			value, err := families.Value(tc.name, tc.labels)
+			require.NoError(t, err, tc.name)
+			assert.Equal(t, tc.value, value, tc.name)
+		}
+
+		_, err = families.Value("foo_requests_total", prometheus.Labels{"endpoint": "/bar"})
+		assert.EqualError(t, err, `metric not found: foo_requests_total{endpoint="/bar"}`)
+
+		s.Close()
+		_, err = tools.Scrape(s.URL)
+		assert.Error(t, err)
+	}
+}
+
+func TestParse_OpenMetrics(t *testing.T) {
+	families, err := tools.Parse(strings.NewReader(`# HELP foo Foo.
+# TYPE foo counter
+# UNIT foo seconds
+foo_total{a="b # {c}"} 17.0 1520879607.789 # {trace_id="oHg5SJYRHA0"} 9.8 1520879607.789
+foo_created{a="b # {c}"} 1520430000.123
+# TYPE bar info
+bar_info{version="1.0"} 1
+# TYPE snafu stateset
+snafu{snafu="a"} 1
+snafu{snafu="b"} 0
+# EOF
+`), "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	require.NoError(t, err)
+	require.Len(t, families, 3)
+
+	value, err := families.Value("foo_total", prometheus.Labels{"a": "b # {c}"})
+	require.NoError(t, err)
+	assert.Equal(t, 17.0, value)
+	assert.Equal(t, "Foo.", families["foo_total"].GetHelp())
+	assert.Equal(t, int64(1520879607789), families["foo_total"].GetMetric()[0].GetTimestampMs())
+
+	value, err = families.Value("bar_info", prometheus.Labels{"version": "1.0"})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, value)
+
+	value, err = families.Value("snafu", prometheus.Labels{"snafu": "b"})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, value)
+
+	_, err = tools.Parse(strings.NewReader("foo{a=\"b\n"), "application/openmetrics-text")
+	assert.Error(t, err)
+}
@@ -3,16 +3,13 @@ package tools
 import (
 	"github.com/prometheus/client_golang/prometheus"
 	pcg "github.com/prometheus/client_model/go"
-	"regexp"
 )
 
-// MetricName returns the name of the provided Prometheus metric.
+// MetricName returns the name of the provided Prometheus metric. Use MetricDescriptor to get the metric's
+// help text, type and labels.
 func MetricName(metric prometheus.Metric) (name string) {
-	desc := metric.Desc().String()
-	r := regexp.MustCompile(`fqName: "([a-z,_]+)"`)
-	match := r.FindStringSubmatch(desc)
-	if len(match) >= 2 {
-		name = match[1]
+	if family, err := gather(metric); err == nil {
+		name = family.GetName()
 	}
 	return
 }